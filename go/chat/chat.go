@@ -29,15 +29,7 @@ func AddMessage(project *core.Project, message Message, attachments []io.ReadClo
 
 	for idx, attachment := range attachments {
 		filename := filepath.Join(folder, fmt.Sprintf("%s.%x.bin", message.Id, idx))
-		writer, err := os.Create(filename)
-		if err != nil {
-			logrus.Warnf("Cannot open file %s for writing: %v", filename, err)
-			return err
-		}
-		defer writer.Close()
-
-		_, err = io.Copy(writer, attachment)
-		if err != nil {
+		if err := core.StoreFile(project, filename, attachment); err != nil {
 			logrus.Warnf("cannot write file %s in chat: %v", filename, err)
 			return err
 		}
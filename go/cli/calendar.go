@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"almost-scrum/core"
+	"github.com/fatih/color"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+func listCalendar(project *core.Project) {
+	config := project.Config.Public.Calendar
+	workingDays := config.WorkingDays
+	if len(workingDays) == 0 {
+		workingDays = core.DefaultWorkingDays
+	}
+
+	color.Green("\n  Working days: %s", strings.Join(workingDays, ", "))
+	color.Green("\n  %-14v%s", "Holiday", "Name")
+	for _, holiday := range config.Holidays {
+		color.Yellow("  %-14v%s", holiday.Date, holiday.Name)
+	}
+
+	userInfo, err := core.GetUserInfo(project, core.GetSystemUser())
+	if err == nil && len(userInfo.Absences) > 0 {
+		color.Green("\n  %-14v%-14v%s", "Absent from", "To", "Reason")
+		for _, absence := range userInfo.Absences {
+			color.Yellow("  %-14v%-14v%s", absence.From, absence.To, absence.Reason)
+		}
+	}
+}
+
+func parseDay(s string) time.Time {
+	day, err := time.Parse(core.DateLayout, s)
+	abortIf(err, "Invalid date, use the format YYYY-MM-DD: %v")
+	return day
+}
+
+func addHoliday(project *core.Project, args []string) {
+	if len(args) < 1 {
+		color.Red("Provide the date of the holiday and optionally a name")
+		return
+	}
+	parseDay(args[0])
+
+	config := project.Config.Public.Calendar
+	for _, holiday := range config.Holidays {
+		if holiday.Date == args[0] {
+			color.Red("%s is already a holiday", args[0])
+			return
+		}
+	}
+	config.Holidays = append(config.Holidays, core.Holiday{Date: args[0], Name: strings.Join(args[1:], " ")})
+	abortIf(core.SetCalendar(project, config), "Cannot save the calendar: %v")
+	color.Green("Holiday %s added", args[0])
+}
+
+func delHoliday(project *core.Project, args []string) {
+	if len(args) != 1 {
+		color.Red("Provide the date of the holiday to delete")
+		return
+	}
+
+	config := project.Config.Public.Calendar
+	for i, holiday := range config.Holidays {
+		if holiday.Date == args[0] {
+			config.Holidays = append(config.Holidays[0:i], config.Holidays[i+1:]...)
+			abortIf(core.SetCalendar(project, config), "Cannot save the calendar: %v")
+			color.Green("Holiday %s deleted", args[0])
+			return
+		}
+	}
+	color.Red("%s is not a holiday", args[0])
+}
+
+func importCalendar(project *core.Project, args []string) {
+	if len(args) != 1 {
+		color.Red("Provide the iCalendar file to import")
+		return
+	}
+	data, err := ioutil.ReadFile(args[0])
+	abortIf(err, "Cannot read the file: %v")
+
+	added, err := core.ImportICS(project, data)
+	abortIf(err, "Cannot import the calendar: %v")
+	color.Green("%d holidays imported", added)
+}
+
+func addAbsence(project *core.Project, args []string) {
+	if len(args) < 2 {
+		color.Red("Provide the first and the last day of the absence and optionally a reason")
+		return
+	}
+	parseDay(args[0])
+	parseDay(args[1])
+
+	user := core.GetSystemUser()
+	userInfo, err := core.GetUserInfo(project, user)
+	abortIf(err, "Cannot read the user: %v")
+
+	absences := append(userInfo.Absences, core.Absence{From: args[0], To: args[1], Reason: strings.Join(args[2:], " ")})
+	abortIf(core.SetAbsences(project, user, absences), "Invalid absence: %v")
+	color.Green("Absence from %s to %s added", args[0], args[1])
+}
+
+func showCapacity(project *core.Project, args []string) {
+	if len(args) != 2 {
+		color.Red("Provide the first day and the day after the end of the period")
+		return
+	}
+	from, to := parseDay(args[0]), parseDay(args[1])
+
+	calendar := core.GetCalendar(project)
+	users := core.GetUserList(project)
+	capacity := calendar.Capacity(users, from, to)
+	color.Green("\n  Working days: %d", calendar.WorkingDays("", from, to))
+	color.Green("\n  %-20v%s", "User", "Days")
+	for _, user := range users {
+		color.Yellow("  %-20v%d", user, capacity[user])
+	}
+}
+
+func processCalendar(projectPath string, args []string) {
+	project := getProject(projectPath)
+
+	if len(args) == 0 {
+		listCalendar(project)
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "holiday":
+		if len(args) > 1 && args[1] == "del" {
+			delHoliday(project, args[2:])
+		} else if len(args) > 1 && args[1] == "add" {
+			addHoliday(project, args[2:])
+		} else {
+			addHoliday(project, args[1:])
+		}
+	case "import":
+		importCalendar(project, args[1:])
+	case "absent":
+		addAbsence(project, args[1:])
+	case "capacity":
+		showCapacity(project, args[1:])
+	default:
+		color.Red("Unknown calendar command %s", args[0])
+	}
+}
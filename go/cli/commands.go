@@ -17,18 +17,34 @@ func usage() {
 	fmt.Printf("usage: scrum [-p <project-path>] [-u <user>] [-v] [-a] <command> [<args>]\n\n" +
 		"These are the common Scrum commands used in various situations.\n" +
 		"\tinit              Initialize a project in the project path\n" +
-		"\ttop [n]           Show top stories in current store\n" +
+		"\ttop [n] [query]   Show top stories in current store\n" +
+		"\tls [query]        List the tasks that match the query, e.g. type:feature status:!#Done\n" +
+		"\texport <format> [-o file] [-f fields] [-holidays] [query]  Export tasks as csv, jsonl, md, xlsx or ics\n" +
+		"\tfilter [name]     List the saved filters or run the filter with the given name\n" +
+		"\tfilter save|share <name> <query>  Save a personal or shared filter\n" +
+		"\tfilter del|unshare <name>         Delete a personal or shared filter\n" +
 		"\tnew [title]       Create a task\n" +
 		"\tedit [name]       Edit a task\n" +
 		"\tdel [name]        Delete a task\n" +
 		"\ttouch [name]      Focus on a task\n" +
 		"\tmove [name]       Rename or move a task to a different board\n" +
 		"\towner [name]      Assign the story to another user\n" +
+		"\tworkload [day|week] [from] [to]      Show the allocation of each user against the capacity\n" +
 		"\tcommit            Commit changes to the git repository\n" +
 		"\tboard             List the boards and set the default\n" +
 		"\tboard new <name>  Create a board with the provided name\n" +
 		"\tusers add <id>    Add a user to current project\n" +
 		"\tusers del <id>    Remove a user to current project\n" +
+		"\tcalendar          Show working days, holidays and your absences\n" +
+		"\tcalendar holiday [del] <date> [name]  Add or delete a holiday\n" +
+		"\tcalendar import <file.ics>           Import holidays from an iCalendar file\n" +
+		"\tcalendar absent <from> <to> [reason] Add an absence for the current user\n" +
+		"\tcalendar capacity <from> <to>        Show the working days of each user\n" +
+		"\tlibrary diff <from> <to> [html]      Compare two versions of a library document\n" +
+		"\tlibrary checkout <path> [hours]      Lock a library document for editing\n" +
+		"\tlibrary checkin <path> [file]        Release the lock and create the next version\n" +
+		"\tlibrary unlock <path>                Release or break the lock on a document\n" +
+		"\tstorage [gc|dedup]                   Show the storage usage, remove unused or share identical content\n" +
 		"\tfed sync	[days]   Sync the project with the Federation. Optionally #days to consider \n" +
 		"\tfed join          Join the Federation\n" +
 		"\tfed share <file>  Make a file public to the Federation\n" +
@@ -45,6 +61,7 @@ func usage() {
 var shortcuts = map[byte]string{
 	'i': "init",
 	't': "top",
+	'l': "ls",
 	'n': "new",
 	'e': "edit",
 	'd': "del",
@@ -172,6 +189,14 @@ func ProcessArgs() {
 		processPwd(commands[1:])
 	case "top":
 		processTop(projectPath, global, commands[1:])
+	case "ls":
+		processList(projectPath, global, commands[1:])
+	case "calendar":
+		processCalendar(projectPath, commands[1:])
+	case "filter":
+		processFilter(projectPath, commands[1:])
+	case "export":
+		processExport(projectPath, global, commands[1:])
 	case "new":
 		processNew(projectPath, commands[1:])
 	case "edit":
@@ -182,6 +207,12 @@ func ProcessArgs() {
 		processOwner(projectPath, global, commands[1:])
 	case "move":
 		processMove(projectPath, global, commands[1:])
+	case "library":
+		processLibrary(projectPath, commands[1:])
+	case "storage":
+		processStorage(projectPath, commands[1:])
+	case "workload":
+		processWorkload(projectPath, commands[1:])
 	case "commit":
 		processCommit(projectPath, global)
 	case "fed":
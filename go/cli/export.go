@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"almost-scrum/query"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// processExport writes the tasks that match the query in a file or on the standard output.
+// Usage: export csv|jsonl|md|xlsx|ics [-o <file>] [-f <field,field>] [-holidays] [query]. The holidays of
+// the project are included only in the ics format.
+func processExport(projectPath string, global bool, args []string) {
+	if len(args) == 0 {
+		color.Red("Provide the format: csv, jsonl, md, xlsx or ics")
+		return
+	}
+	format := strings.ToLower(args[0])
+	if _, found := query.ExportContentTypes[format]; !found {
+		color.Red("Unsupported format %s. Use csv, jsonl, md, xlsx or ics", format)
+		return
+	}
+	args = args[1:]
+
+	var output string
+	var columns []string
+	var holidays bool
+	for len(args) > 0 {
+		if args[0] == "-holidays" {
+			holidays = true
+			args = args[1:]
+			continue
+		}
+		if len(args) < 2 || (args[0] != "-o" && args[0] != "-f") {
+			break
+		}
+		if args[0] == "-o" {
+			output = args[1]
+		} else {
+			columns = strings.Split(args[1], ",")
+		}
+		args = args[2:]
+	}
+
+	project := getProject(projectPath)
+	q, err := query.Parse(joinQueryArgs(args))
+	abortIf(err, "Invalid query: %v")
+	if board := getBoard(project, global); board != "" && len(q.WhereBoardIs) == 0 {
+		q.WhereBoardIs = []string{board}
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		abortIf(err, "Cannot create file: %v")
+		defer file.Close()
+		w = file
+	} else if format == query.FormatXLSX {
+		color.Red("Provide a file with -o for the xlsx format")
+		return
+	}
+
+	if format == query.FormatICS {
+		err = query.ExportICS(project, q, holidays, w)
+	} else {
+		err = query.Export(project, q, format, columns, w)
+	}
+	abortIf(err, "Cannot export tasks: %v")
+	if output != "" {
+		color.Green("Tasks exported to %s", output)
+	}
+}
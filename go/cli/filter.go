@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"almost-scrum/core"
+	"almost-scrum/query"
+	"github.com/fatih/color"
+	"strings"
+)
+
+func listFilters(project *core.Project) {
+	filters := core.ListFilters(project, core.GetSystemUser())
+
+	color.Green("\n  %-20v%-10v%s", "Filter", "Scope", "Query")
+	for _, filter := range filters.Personal {
+		color.Yellow("  %-20v%-10v%s", filter.Name, "personal", filter.Query)
+	}
+	for _, filter := range filters.Shared {
+		color.Yellow("  %-20v%-10v%s", filter.Name, "shared", filter.Query)
+	}
+}
+
+func saveFilter(project *core.Project, shared bool, args []string) {
+	if len(args) < 2 {
+		color.Red("Provide the name of the filter and the query")
+		return
+	}
+	filter := core.Filter{
+		Name:  args[0],
+		Query: joinQueryArgs(args[1:]),
+	}
+	_, err := query.Parse(filter.Query)
+	abortIf(err, "Invalid query: %v")
+
+	abortIf(core.SetFilter(project, core.GetSystemUser(), filter, shared), "")
+	color.Green("Filter %s saved", filter.Name)
+}
+
+func deleteFilter(project *core.Project, shared bool, args []string) {
+	if len(args) != 1 {
+		color.Red("Provide the name of the filter to delete")
+		return
+	}
+	abortIf(core.DeleteFilter(project, core.GetSystemUser(), args[0], shared), "Cannot delete filter: %v")
+	color.Green("Filter %s deleted", args[0])
+}
+
+func processFilter(projectPath string, args []string) {
+	project := getProject(projectPath)
+
+	if len(args) == 0 {
+		listFilters(project)
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "save":
+		saveFilter(project, false, args[1:])
+	case "share":
+		saveFilter(project, true, args[1:])
+	case "del":
+		deleteFilter(project, false, args[1:])
+	case "unshare":
+		deleteFilter(project, true, args[1:])
+	default:
+		refs, err := query.RunFilter(project, core.GetSystemUser(), args[0])
+		abortIf(err, "Cannot run filter: %v")
+		printTasks(refs, -1)
+	}
+}
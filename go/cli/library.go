@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"almost-scrum/core"
+	"almost-scrum/library"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func diffVersions(project *core.Project, args []string) {
+	if len(args) < 2 {
+		color.Red("Usage: library diff <from> <to> [html], e.g. library diff /Specs/Spec~0.2.docx /Specs/Spec~0.3.docx")
+		return
+	}
+
+	diff, err := library.CompareVersions(project, args[0], args[1])
+	abortIf(err, "Cannot compare the versions: %v")
+	if len(args) > 2 && args[2] == "html" {
+		fmt.Println(diff.HTML)
+		return
+	}
+
+	for _, line := range strings.SplitAfter(diff.Unified, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			color.New(color.Bold).Print(line)
+		case strings.HasPrefix(line, "@@"):
+			color.New(color.FgCyan).Print(line)
+		case strings.HasPrefix(line, "+"):
+			color.New(color.FgGreen).Print(line)
+		case strings.HasPrefix(line, "-"):
+			color.New(color.FgRed).Print(line)
+		default:
+			fmt.Print(line)
+		}
+	}
+	color.Yellow("\n  %d lines added, %d lines removed", diff.Added, diff.Removed)
+}
+
+func checkOut(project *core.Project, args []string) {
+	if len(args) < 1 {
+		color.Red("Usage: library checkout <path> [hours], e.g. library checkout /Specs/Spec~0.3.docx 8")
+		return
+	}
+	hours := 0
+	if len(args) > 1 {
+		var err error
+		hours, err = strconv.Atoi(args[1])
+		abortIf(err, "Invalid number of hours: %v")
+	}
+
+	lock, err := library.CheckOut(project, args[0], core.GetSystemUser(), time.Duration(hours)*time.Hour)
+	abortIf(err, "Cannot check out the document: %v")
+	color.Green("%s checked out until %s", args[0], lock.Expires.Format("2006-01-02 15:04"))
+}
+
+func checkIn(project *core.Project, args []string) {
+	if len(args) < 1 {
+		color.Red("Usage: library checkin <path> [file], e.g. library checkin /Specs/Spec~0.3.docx ~/Spec.docx")
+		return
+	}
+	var reader io.ReadCloser
+	if len(args) > 1 {
+		file, err := os.Open(args[1])
+		abortIf(err, "Cannot open the file: %v")
+		defer file.Close()
+		reader = file
+	}
+
+	path, err := library.CheckIn(project, args[0], core.GetSystemUser(), reader, false)
+	abortIf(err, "Cannot check in the document: %v")
+	color.Green("%s checked in as %s", args[0], path)
+}
+
+func unlock(project *core.Project, args []string) {
+	if len(args) < 1 {
+		color.Red("Usage: library unlock <path>")
+		return
+	}
+	abortIf(library.Unlock(project, args[0], core.GetSystemUser()), "Cannot unlock the document: %v")
+	color.Green("%s unlocked", args[0])
+}
+
+func processLibrary(projectPath string, args []string) {
+	project := getProject(projectPath)
+
+	if len(args) == 0 {
+		color.Red("Missing library command")
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "diff":
+		diffVersions(project, args[1:])
+	case "checkout":
+		checkOut(project, args[1:])
+	case "checkin":
+		checkIn(project, args[1:])
+	case "unlock":
+		unlock(project, args[1:])
+	default:
+		color.Red("Unknown library command %s", args[0])
+	}
+}
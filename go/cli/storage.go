@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"almost-scrum/core"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func showStorage(project *core.Project) {
+	usage := core.GetStorageUsage(project)
+	color.Green("\n  %-12v%-10v%-12v%s", "Folder", "Files", "Size", "Shared")
+	for _, folder := range usage.Folders {
+		fmt.Printf("  %-12v%-10v%-12v%s\n", folder.Folder, folder.Files, formatSize(folder.Size),
+			formatSize(folder.Shared))
+	}
+	color.Green("\n  Blobs: %d, %s", usage.Blobs, formatSize(usage.BlobsSize))
+	if usage.Unreferenced > 0 {
+		color.Yellow("  Unreferenced blobs: %d, %s. Run 'storage gc' to remove them", usage.Unreferenced,
+			formatSize(usage.UnreferencedSize))
+	}
+	color.Green("  Total %s, on disk %s, saved %s", formatSize(usage.Size), formatSize(usage.DiskSize),
+		formatSize(usage.Saved))
+}
+
+// processStorage shows the storage used by the project and maintains the blob store.
+// Usage: storage [gc|dedup]
+func processStorage(projectPath string, args []string) {
+	project := getProject(projectPath)
+
+	if len(args) == 0 {
+		showStorage(project)
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "gc":
+		removed, freed := core.CollectBlobs(project)
+		color.Green("Removed %d unreferenced blobs, %s freed", removed, formatSize(freed))
+	case "dedup":
+		linked, err := core.Deduplicate(project)
+		abortIf(err, "Cannot deduplicate the files: %v")
+		color.Green("Moved %d files to the blob store", linked)
+	default:
+		color.Red("Unknown storage command %s", args[0])
+	}
+}
@@ -2,12 +2,54 @@ package cli
 
 import (
 	"almost-scrum/core"
+	"almost-scrum/query"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
 )
 
+// joinQueryArgs rebuilds the textual query from the command line, restoring the quotes removed by the shell
+func joinQueryArgs(args []string) string {
+	terms := make([]string, 0, len(args))
+	for _, arg := range args {
+		if strings.ContainsAny(arg, " \t") && !strings.Contains(arg, "\"") {
+			if idx := strings.Index(arg, ":"); idx > 0 && !strings.ContainsAny(arg[0:idx], " \t") {
+				arg = arg[0:idx+1] + "\"" + arg[idx+1:] + "\""
+			} else {
+				arg = "\"" + arg + "\""
+			}
+		}
+		terms = append(terms, arg)
+	}
+	return strings.Join(terms, " ")
+}
+
+func queryTasks(project *core.Project, global bool, args []string) []query.TaskRef {
+	q, err := query.Parse(joinQueryArgs(args))
+	abortIf(err, "Invalid query: %v")
+
+	if board := getBoard(project, global); board != "" && len(q.WhereBoardIs) == 0 {
+		q.WhereBoardIs = []string{board}
+	}
+	refs, err := query.QueryTasks(project, q)
+	abortIf(err, "")
+	return refs
+}
+
+func printTasks(refs []query.TaskRef, n int) {
+	color.Green("\n  %-40v%-20v%s", "Task", "Board", "Date")
+	for i, ref := range refs {
+		if n >= 0 && i >= n {
+			break
+		}
+		tm := ref.ModTime.Format(time.RFC822)
+		color.Yellow("  %-40v%-20v%s", ref.Name, ref.Board, tm)
+	}
+	color.Green("  Total %d", len(refs))
+}
+
 func processTop(projectPath string, global bool, args []string) {
 	n := 7
 	if len(args) > 0 {
@@ -19,17 +61,10 @@ func processTop(projectPath string, global bool, args []string) {
 	}
 
 	project := getProject(projectPath)
-	board := getBoard(project, global)
-	infos, err := core.SearchTask(project, board, true, args...)
-	abortIf(err, "")
+	printTasks(queryTasks(project, global, args), n)
+}
 
-	color.Green("\n  %-40v%-20v%s", "Task", "Board", "Date")
-	for i, info := range infos {
-		if i >= n {
-			break
-		}
-		tm := info.ModTime.Format(time.RFC822)
-		color.Yellow("  %-40v%-20v%s", info.Name, info.Board, tm)
-	}
-	color.Green("  Total %d", len(infos))
+func processList(projectPath string, global bool, args []string) {
+	project := getProject(projectPath)
+	printTasks(queryTasks(project, global, args), -1)
 }
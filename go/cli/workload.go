@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"almost-scrum/gantt"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// processWorkload shows the effort allocated to each user against the capacity, by day or by week.
+// Usage: workload [day|week] [from] [to]
+func processWorkload(projectPath string, args []string) {
+	period := gantt.PeriodDay
+	if len(args) > 0 && (args[0] == gantt.PeriodDay || args[0] == gantt.PeriodWeek) {
+		period = args[0]
+		args = args[1:]
+	}
+
+	from := time.Now()
+	if len(args) > 0 {
+		from = parseDay(args[0])
+	}
+	to := from.AddDate(0, 0, 14)
+	if period == gantt.PeriodWeek {
+		to = from.AddDate(0, 0, 56)
+	}
+	if len(args) > 1 {
+		to = parseDay(args[1])
+	}
+
+	project := getProject(projectPath)
+	workload, err := gantt.GetWorkload(project, from, to, period)
+	abortIf(err, "Cannot compute the workload: %v")
+	if len(workload.Users) == 0 || len(workload.Users[0].Loads) == 0 {
+		color.Yellow("No users or no days in the period")
+		return
+	}
+
+	layout := "Mon 02"
+	if period == gantt.PeriodWeek {
+		layout = "Jan 02"
+	}
+	var header strings.Builder
+	for _, load := range workload.Users[0].Loads {
+		header.WriteString(fmt.Sprintf("%-10v", load.Start.Format(layout)))
+	}
+	color.Green("\n  %-16v%s", "User", header.String())
+
+	for _, user := range workload.Users {
+		fmt.Printf("  %-16v", user.User)
+		for _, load := range user.Loads {
+			cell := fmt.Sprintf("%-10v", fmt.Sprintf("%g/%g", load.Allocated, load.Capacity))
+			if load.Overallocated {
+				color.New(color.FgRed).Print(cell)
+			} else {
+				fmt.Print(cell)
+			}
+		}
+		fmt.Println()
+	}
+
+	for _, user := range workload.Users {
+		for _, load := range user.Loads {
+			if !load.Overallocated {
+				continue
+			}
+			color.Red("\n  %s is overallocated on %s: %g days of %g", user.User,
+				load.Start.Format("2006-01-02"), load.Allocated, load.Capacity)
+			for _, task := range load.Tasks {
+				color.Yellow("    %-40v%g", task.Board+"/"+task.Name, task.Effort)
+			}
+		}
+	}
+}
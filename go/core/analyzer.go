@@ -0,0 +1,183 @@
+package core
+
+import (
+	"github.com/blevesearch/snowballstem"
+	enStem "github.com/blevesearch/snowballstem/english"
+	frStem "github.com/blevesearch/snowballstem/french"
+	deStem "github.com/blevesearch/snowballstem/german"
+	itStem "github.com/blevesearch/snowballstem/italian"
+	esStem "github.com/blevesearch/snowballstem/spanish"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultLanguage is used when the project configuration does not define any language
+const DefaultLanguage = "en"
+
+type language struct {
+	stopWords map[string]string
+	stem      func(env *snowballstem.Env) bool
+}
+
+var languages = map[string]language{
+	"en": {english, enStem.Stem},
+	"it": {italian, itStem.Stem},
+	"de": {german, deStem.Stem},
+	"fr": {french, frStem.Stem},
+	"es": {spanish, esStem.Stem},
+}
+
+// Analyzer converts text in the keys stored in the index. It removes stop words, reduces words
+// to their stem and folds diacritics. When more languages are configured, a word is reduced
+// with the stemmer of each language.
+type Analyzer struct {
+	Languages []string
+	stopWords map[string]string
+	stemmers  []func(env *snowballstem.Env) bool
+	// configured are the stop words from the project configuration, sorted
+	configured []string
+}
+
+// NewAnalyzer creates an analyzer for the given languages (e.g. en, it) and additional stop words.
+func NewAnalyzer(codes []string, stopWords []string) *Analyzer {
+	analyzer := &Analyzer{
+		Languages: []string{},
+		stopWords: make(map[string]string),
+	}
+
+	for _, code := range codes {
+		code = strings.ToLower(code)
+		l, found := languages[code]
+		if !found {
+			logrus.Warnf("unsupported language %s in index analyzer", code)
+			continue
+		}
+		analyzer.addLanguage(code, l)
+	}
+	if len(analyzer.Languages) == 0 {
+		analyzer.addLanguage(DefaultLanguage, languages[DefaultLanguage])
+	}
+
+	analyzer.AddStopWords(stopWords)
+	for _, word := range stopWords {
+		if word = strings.ToLower(word); !HasStringInSlice(analyzer.configured, word) {
+			analyzer.configured = append(analyzer.configured, word)
+		}
+	}
+	sort.Strings(analyzer.configured)
+	return analyzer
+}
+
+func (a *Analyzer) addLanguage(code string, l language) {
+	a.Languages = append(a.Languages, code)
+	a.stemmers = append(a.stemmers, l.stem)
+	for word := range l.stopWords {
+		a.stopWords[word] = ""
+	}
+}
+
+// Signature identifies the configuration of the analyzer, i.e. the languages and the stop words in
+// the project configuration. An index built with a different signature must be rebuilt.
+func (a *Analyzer) Signature() string {
+	if len(a.configured) == 0 {
+		return strings.Join(a.Languages, ",")
+	}
+	return strings.Join(a.Languages, ",") + "|" + strings.Join(a.configured, ",")
+}
+
+// AddStopWords adds words that must be ignored by the analyzer.
+func (a *Analyzer) AddStopWords(words []string) {
+	for _, word := range words {
+		a.stopWords[strings.ToLower(word)] = ""
+	}
+}
+
+// IsStopWord returns true when the word is ignored by the analyzer.
+func (a *Analyzer) IsStopWord(word string) bool {
+	_, found := a.stopWords[strings.ToLower(word)]
+	return found
+}
+
+// Keys returns the index keys for a single word. Words starting with @ or # are kept as they are.
+func (a *Analyzer) Keys(word string) []string {
+	if strings.HasPrefix(word, "@") || strings.HasPrefix(word, "#") {
+		return []string{word}
+	}
+
+	word = strings.ToLower(word)
+	keys := make([]string, 0, len(a.stemmers))
+	env := snowballstem.NewEnv("")
+	for _, stem := range a.stemmers {
+		env.SetCurrent(word)
+		stem(env)
+		key := FoldDiacritics(env.Current())
+		if _, found := a.stopWords[key]; found {
+			continue
+		}
+		if !HasStringInSlice(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Analyze splits the text in words and returns the keys for normal and special words
+// (i.e. users and tags). Forms maps the surface form of each normal word to its first key.
+func (a *Analyzer) Analyze(text []byte) (normal []string, special []string, forms map[string]string) {
+	normal = make([]string, 0)
+	special = make([]string, 0)
+	forms = make(map[string]string)
+
+	text = norm.NFC.Bytes(text)
+	words := wordSegment.FindAll(text, -1)
+	for _, w := range words {
+		s := string(w)
+		if s[0] == '@' || s[0] == '#' {
+			special = append(special, s)
+			continue
+		}
+
+		s = strings.ToLower(s)
+		if a.IsStopWord(s) {
+			continue
+		}
+		keys := a.Keys(s)
+		if len(keys) > 0 {
+			forms[s] = keys[0]
+		}
+		normal = append(normal, keys...)
+	}
+	return normal, special, forms
+}
+
+// FoldDiacritics removes accents and other marks from the text, e.g. perché becomes perche.
+func FoldDiacritics(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return folded
+}
+
+// SetAnalyzer creates the analyzer from the project configuration. When the configuration
+// changes the way text is analyzed, the index is discarded so that the next ReIndex rebuilds it.
+// It returns true when the index must be rebuilt.
+func SetAnalyzer(project *Project) bool {
+	analyzer := NewAnalyzer(project.Config.Public.Languages, project.Config.Public.StopWords)
+
+	project.IndexMutex.Lock()
+	defer project.IndexMutex.Unlock()
+
+	project.Analyzer = analyzer
+	if project.Index != nil && project.Index.Analyzer != analyzer.Signature() {
+		project.Index = nil
+		return true
+	}
+	return false
+}
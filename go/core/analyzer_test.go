@@ -0,0 +1,43 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzerStemming(t *testing.T) {
+	analyzer := NewAnalyzer([]string{"en"}, nil)
+	assert.Equal(t, analyzer.Keys("deployed"), analyzer.Keys("deployment"))
+	assert.Equal(t, analyzer.Keys("deploy"), analyzer.Keys("deploying"))
+	assert.Equal(t, []string{"@bob"}, analyzer.Keys("@bob"))
+}
+
+func TestAnalyzerLanguages(t *testing.T) {
+	analyzer := NewAnalyzer([]string{"it", "en"}, []string{"ticket"})
+	assert.Equal(t, "it,en|ticket", analyzer.Signature())
+	assert.Equal(t, "it,en|bug,ticket", NewAnalyzer([]string{"it", "en"}, []string{"Ticket", "bug"}).Signature())
+	assert.True(t, analyzer.IsStopWord("perché"))
+	assert.True(t, analyzer.IsStopWord("the"))
+	assert.True(t, analyzer.IsStopWord("Ticket"))
+
+	normal, special, forms := analyzer.Analyze([]byte("Il rilascio del #backend è pronto per @bob"))
+	assert.Equal(t, []string{"#backend", "@bob"}, special)
+	assert.Contains(t, forms, "rilascio")
+	assert.NotContains(t, normal, "il")
+}
+
+func TestFoldDiacritics(t *testing.T) {
+	assert.Equal(t, "perche", FoldDiacritics("perché"))
+	assert.Equal(t, "uber", FoldDiacritics("über"))
+}
+
+func TestUnsupportedLanguage(t *testing.T) {
+	analyzer := NewAnalyzer([]string{"xx"}, nil)
+	assert.Equal(t, DefaultLanguage, analyzer.Signature())
+}
+
+func TestIndexedKeys(t *testing.T) {
+	project := &Project{Analyzer: NewAnalyzer([]string{"en"}, nil)}
+	assert.Equal(t, []string{"login", "app"}, IndexedKeys(project, []string{"login", "to", "app"}))
+}
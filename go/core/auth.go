@@ -1,6 +1,9 @@
 package core
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
@@ -58,3 +61,40 @@ func CheckUser(user, password string) bool {
 	err := bcrypt.CompareHashAndPassword(hash, []byte(password))
 	return err == nil
 }
+
+func hashFeedToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// NewFeedToken creates the token that gives access to the calendar feeds of the user and revokes
+// the previous one. Only the hash of the token is saved in the project.
+func NewFeedToken(project *Project, user string) (string, error) {
+	userInfo, err := GetUserInfo(project, user)
+	if err != nil {
+		return "", err
+	}
+
+	var key [24]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(key[:])
+	userInfo.FeedToken = hashFeedToken(token)
+	return token, SetUserInfo(project, user, &userInfo)
+}
+
+// GetFeedUser returns the user that owns the feed token
+func GetFeedUser(project *Project, token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	hash := []byte(hashFeedToken(token))
+	for _, user := range GetUserList(project) {
+		userInfo, err := GetUserInfo(project, user)
+		if err == nil && subtle.ConstantTimeCompare(hash, []byte(userInfo.FeedToken)) == 1 {
+			return user, true
+		}
+	}
+	return "", false
+}
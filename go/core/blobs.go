@@ -0,0 +1,346 @@
+package core
+
+import (
+	"almost-scrum/fs"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/code-to-go/fed"
+	"github.com/code-to-go/fed/extfs"
+	"github.com/sirupsen/logrus"
+)
+
+// BlobGracePeriod protects recent blobs from the garbage collection while they are linked
+const BlobGracePeriod = time.Hour
+
+// BlobFolders are the folders whose files reference blobs
+var BlobFolders = []string{ProjectLibraryFolder, ProjectArchiveFolder, ProjectChatFolder}
+
+// StorageFolders are the folders included in the storage usage
+var StorageFolders = []string{ProjectLibraryFolder, ProjectArchiveFolder, ProjectChatFolder, ProjectTrashFolder}
+
+// FolderUsage is the storage used by the files in a folder. Shared is the size of the files whose
+// content is in the blob store.
+type FolderUsage struct {
+	Folder string `json:"folder"`
+	Files  int    `json:"files"`
+	Size   int64  `json:"size"`
+	Shared int64  `json:"shared"`
+}
+
+// StorageUsage reports the storage used by a project. Size is the total of the files as they appear
+// in the folders; DiskSize is what they take on disk once identical content is stored only once.
+type StorageUsage struct {
+	Folders          []FolderUsage `json:"folders"`
+	Blobs            int           `json:"blobs"`
+	BlobsSize        int64         `json:"blobsSize"`
+	Unreferenced     int           `json:"unreferenced"`
+	UnreferencedSize int64         `json:"unreferencedSize"`
+	Size             int64         `json:"size"`
+	DiskSize         int64         `json:"diskSize"`
+	Saved            int64         `json:"saved"`
+}
+
+// BlobPath returns the location of the blob with the given hash
+func BlobPath(project *Project, hash string) string {
+	return filepath.Join(project.Path, ProjectBlobsFolder, hash[0:2], hash)
+}
+
+// StoreBlob writes the content of the reader in the blob store and returns its hash. Content already
+// in the store is not duplicated. Blobs are read-only.
+func StoreBlob(project *Project, r io.Reader) (string, error) {
+	folder := filepath.Join(project.Path, ProjectBlobsFolder)
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempFile(folder, ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	h256 := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h256), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	hash := hex.EncodeToString(h256.Sum(nil))
+	p := BlobPath(project, hash)
+	if _, err := os.Stat(p); err == nil {
+		// a recent modification time keeps the blob out of the garbage collection until it is linked
+		now := time.Now()
+		_ = os.Chtimes(p, now, now)
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return "", err
+	}
+	return hash, os.Chmod(p, 0444)
+}
+
+func copyFile(source, dest string) error {
+	r, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// replaceFile creates a temporary file next to dest with the create function and moves it on dest
+func replaceFile(dest string, create func(tmp string) error) error {
+	tmp := filepath.Join(filepath.Dir(dest), "."+filepath.Base(dest)+".tmp")
+	_ = os.Remove(tmp)
+	if err := create(tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dest)
+}
+
+// CopyFile writes a copy of source in dest, replacing any existing file
+func CopyFile(source, dest string) error {
+	return replaceFile(dest, func(tmp string) error {
+		return copyFile(source, tmp)
+	})
+}
+
+// WriteFile writes the content of the reader in dest, replacing any existing file. The content is
+// moved on dest only when complete.
+func WriteFile(dest string, r io.Reader) error {
+	return replaceFile(dest, func(tmp string) error {
+		w, err := os.Create(tmp)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, r)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	})
+}
+
+// LinkBlob makes dest a hard link to a blob, replacing any existing file. When the file system does not
+// support hard links, a read-only copy of the blob is made. Since blobs are read-only, dest must not
+// be a file that is changed in place.
+func LinkBlob(project *Project, hash string, dest string) error {
+	blob := BlobPath(project, hash)
+	blobInfo, err := os.Stat(blob)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(dest); err == nil && os.SameFile(info, blobInfo) {
+		return nil
+	}
+
+	return replaceFile(dest, func(tmp string) error {
+		if err := os.Link(blob, tmp); err != nil {
+			logrus.Debugf("cannot link blob %s to %s, copying instead: %v", hash, dest, err)
+			if err := copyFile(blob, tmp); err != nil {
+				return err
+			}
+			return os.Chmod(tmp, 0444)
+		}
+		return nil
+	})
+}
+
+// StoreFile writes the content of the reader in the blob store and links dest to the blob. Like the
+// blob, dest is read-only and is replaced, not changed in place, when the content changes.
+func StoreFile(project *Project, dest string, r io.Reader) error {
+	hash, err := StoreBlob(project, r)
+	if err != nil {
+		return err
+	}
+	return LinkBlob(project, hash, dest)
+}
+
+// ShareFile links dest to the content of source, which is moved to the blob store when it is not
+// there yet
+func ShareFile(project *Project, source string, dest string) error {
+	hash, err := ImportFile(project, source)
+	if err != nil {
+		return err
+	}
+	return LinkBlob(project, hash, dest)
+}
+
+// CanShare returns false for files that must keep their own content. The federation rewrites tracked
+// files in place when other peers change them, and chat messages change when users like them, so
+// only the attachments of the chat are shared.
+func CanShare(path string) bool {
+	var attr fed.FedAttr
+	if extfs.Get(path, &attr) && attr.IsTracked {
+		return false
+	}
+	if filepath.Base(filepath.Dir(path)) == ProjectChatFolder {
+		return filepath.Ext(path) == ".bin"
+	}
+	return true
+}
+
+// ImportFile moves the content of an existing file to the blob store and links the file to the blob.
+// The file becomes read-only, so it must be detached before it is changed in place.
+func ImportFile(project *Project, path string) (string, error) {
+	h, err := fs.GetHash(path)
+	if err != nil {
+		return "", err
+	}
+	if h == nil {
+		return "", os.ErrInvalid
+	}
+	hash := hex.EncodeToString(h)
+
+	blob := BlobPath(project, hash)
+	if _, err := os.Stat(blob); err == nil {
+		return hash, LinkBlob(project, hash, path)
+	}
+	if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Link(path, blob); err != nil {
+		logrus.Debugf("cannot link %s to blob %s, copying instead: %v", path, hash, err)
+		if err := copyFile(path, blob); err != nil {
+			return "", err
+		}
+		_ = os.Chmod(blob, 0444)
+	}
+	return hash, os.Chmod(path, 0444)
+}
+
+// DetachFile gives a file its own writable copy of the content, so that changes made in place, e.g. by
+// external applications or by the federation, do not affect other files linked to the same blob
+func DetachFile(path string) error {
+	if links, err := fs.GetLinks(path); err != nil || links < 2 {
+		return err
+	}
+	return CopyFile(path, path)
+}
+
+// Deduplicate links the files that are not in the blob store yet. Files that cannot share their content,
+// e.g. files tracked by the federation, are detached. It returns the number of files that have been linked.
+func Deduplicate(project *Project) (int, error) {
+	cnt := 0
+	for _, folder := range BlobFolders {
+		archive := folder == ProjectArchiveFolder
+		err := filepath.Walk(filepath.Join(project.Path, folder), func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+				return nil
+			}
+			links, err := fs.GetLinks(p)
+			if err != nil {
+				return nil
+			}
+			if !archive && !CanShare(p) {
+				IsErr(DetachFile(p), "cannot detach %s from blob store", p)
+				return nil
+			}
+			if links > 1 {
+				return nil
+			}
+			if _, err := ImportFile(project, p); IsErr(err, "cannot import %s in blob store", p) {
+				return nil
+			}
+			cnt++
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return cnt, err
+		}
+	}
+	return cnt, nil
+}
+
+// walkBlobs calls the function for each blob with the number of files that reference it
+func walkBlobs(project *Project, f func(p string, info os.FileInfo, references uint64)) {
+	_ = filepath.Walk(filepath.Join(project.Path, ProjectBlobsFolder), func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		links, err := fs.GetLinks(p)
+		if err != nil || links == 0 {
+			return nil
+		}
+		f(p, info, links-1)
+		return nil
+	})
+}
+
+// CollectBlobs removes the blobs that are not referenced by any file. It returns the number of removed
+// blobs and the freed space.
+func CollectBlobs(project *Project) (int, int64) {
+	limit := time.Now().Add(-BlobGracePeriod)
+	cnt, freed := 0, int64(0)
+	walkBlobs(project, func(p string, info os.FileInfo, references uint64) {
+		if references > 0 || info.ModTime().After(limit) {
+			return
+		}
+		_ = os.Chmod(p, 0644)
+		if err := os.Remove(p); IsErr(err, "cannot remove blob %s", p) {
+			return
+		}
+		cnt++
+		freed += info.Size()
+	})
+	logrus.Infof("Removed %d unreferenced blobs, %d bytes", cnt, freed)
+	return cnt, freed
+}
+
+// GetStorageUsage returns the storage used by the library, the archive, the chat and the trash
+func GetStorageUsage(project *Project) StorageUsage {
+	var usage StorageUsage
+	var unshared int64
+	for _, folder := range StorageFolders {
+		fu := FolderUsage{Folder: folder}
+		_ = filepath.Walk(filepath.Join(project.Path, folder), func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			fu.Files++
+			fu.Size += info.Size()
+			if links, err := fs.GetLinks(p); err == nil && links > 1 {
+				fu.Shared += info.Size()
+			} else {
+				unshared += info.Size()
+			}
+			return nil
+		})
+		usage.Folders = append(usage.Folders, fu)
+		usage.Size += fu.Size
+	}
+
+	walkBlobs(project, func(p string, info os.FileInfo, references uint64) {
+		usage.Blobs++
+		usage.BlobsSize += info.Size()
+		if references == 0 {
+			usage.Unreferenced++
+			usage.UnreferencedSize += info.Size()
+		}
+	})
+	usage.DiskSize = usage.BlobsSize + unshared
+	usage.Saved = usage.Size - usage.DiskSize + usage.UnreferencedSize
+	return usage
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"almost-scrum/fs"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-to-go/fed"
+	"github.com/code-to-go/fed/extfs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlobs(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "ash-blobs")
+	defer os.RemoveAll(dir)
+	extfs.Init(filepath.Join(dir, "extfs"))
+	project := &Project{Path: dir}
+	for _, folder := range StorageFolders {
+		_ = os.MkdirAll(filepath.Join(dir, folder), 0755)
+	}
+
+	doc := filepath.Join(dir, ProjectLibraryFolder, "spec~0.2.md")
+	assert.Nil(t, WriteFile(doc, strings.NewReader("spec")))
+	public := filepath.Join(dir, ProjectLibraryFolder, "public.md")
+	_ = ioutil.WriteFile(public, []byte("spec"), 0644)
+	_ = extfs.Set(public, fed.FedAttr{IsTracked: true}, false)
+	v1 := filepath.Join(dir, ProjectArchiveFolder, "spec~0.1.md")
+	_ = ioutil.WriteFile(v1, []byte("spec"), 0644)
+	message := filepath.Join(dir, ProjectChatFolder, "1.json")
+	_ = ioutil.WriteFile(message, []byte("spec"), 0644)
+
+	usage := GetStorageUsage(project)
+	assert.Equal(t, 0, usage.Blobs)
+	assert.Equal(t, int64(4*len("spec")), usage.DiskSize)
+
+	// tracked files and chat messages keep their own content
+	cnt, err := Deduplicate(project)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, cnt)
+	usage = GetStorageUsage(project)
+	assert.Equal(t, 1, usage.Blobs)
+	assert.Equal(t, int64(3*len("spec")), usage.DiskSize)
+	for _, p := range []string{public, message} {
+		links, _ := fs.GetLinks(p)
+		assert.Equal(t, uint64(1), links, p)
+	}
+	info, _ := os.Stat(doc)
+	assert.Equal(t, os.FileMode(0444), info.Mode().Perm())
+
+	// attachments are stored once; a detached file can be changed without changing the others
+	attachment := filepath.Join(dir, ProjectChatFolder, "1.0.bin")
+	assert.Nil(t, StoreFile(project, attachment, strings.NewReader("spec")))
+	links, _ := fs.GetLinks(attachment)
+	assert.Equal(t, uint64(4), links)
+	assert.Nil(t, DetachFile(doc))
+	_ = ioutil.WriteFile(doc, []byte("edited"), 0644)
+	data, _ := ioutil.ReadFile(v1)
+	assert.Equal(t, "spec", string(data))
+
+	// a file tracked after it was linked is detached
+	_ = extfs.Set(attachment, fed.FedAttr{IsTracked: true}, false)
+	_, _ = Deduplicate(project)
+	links, _ = fs.GetLinks(attachment)
+	assert.Equal(t, uint64(1), links)
+
+	hash := filepath.Base(BlobPath(project, hashOf(t, v1)))
+	_ = os.Remove(v1)
+	old := time.Now().Add(-2 * BlobGracePeriod)
+	_ = os.Chtimes(BlobPath(project, hash), old, old)
+	removed, freed := CollectBlobs(project)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, int64(len("spec")), freed)
+}
+
+func hashOf(t *testing.T, p string) string {
+	h, err := fs.GetHash(p)
+	assert.Nil(t, err)
+	return hex.EncodeToString(h)
+}
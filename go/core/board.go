@@ -43,8 +43,12 @@ func RenameBoard(project *Project, oldName string, newName string) error {
 	return os.Rename(p, np)
 }
 
+// BoardProperties are the settings of a board. A board with Start and End (e.g. 2021-03-01) is a sprint
+// and End is its last day.
 type BoardProperties struct {
 	TaskTypes []string `json:"taskTypes"`
+	Start     string   `json:"start"`
+	End       string   `json:"end"`
 }
 
 func GetBoardProperties(project *Project, name string) (BoardProperties, error) {
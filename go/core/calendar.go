@@ -0,0 +1,304 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DateLayout is the format of dates in the calendar, holidays and absences
+const DateLayout = "2006-01-02"
+
+// DefaultWorkingDays are used when the project calendar does not define the working days
+var DefaultWorkingDays = []string{"mon", "tue", "wed", "thu", "fri"}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ErrInvalidCalendar is returned when an iCalendar file cannot be parsed
+var ErrInvalidCalendar = errors.New("invalid calendar")
+
+// Holiday is a day when nobody works in the project
+type Holiday struct {
+	Date string `json:"date" yaml:"date"`
+	Name string `json:"name" yaml:"name"`
+}
+
+// Absence is a period, with both days included, when a user does not work
+type Absence struct {
+	From   string `json:"from" yaml:"from"`
+	To     string `json:"to" yaml:"to"`
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+// CalendarConfig is the working calendar of the project. Working days are abbreviations like mon, tue.
+type CalendarConfig struct {
+	WorkingDays []string  `json:"workingDays" yaml:"workingDays"`
+	Holidays    []Holiday `json:"holidays" yaml:"holidays"`
+}
+
+// Calendar tells on which days the users of a project work
+type Calendar struct {
+	workingDays [7]bool
+	holidays    map[string]string
+	absences    map[string][]Absence
+}
+
+// BurndownPoint is the remaining work at the beginning of a day in an ideal burndown
+type BurndownPoint struct {
+	Date      string  `json:"date"`
+	Remaining float64 `json:"remaining"`
+}
+
+func normalizeUser(user string) string {
+	return strings.ToLower(strings.TrimPrefix(user, "@"))
+}
+
+// NewCalendar creates a calendar from the configuration and the absences of the users
+func NewCalendar(config CalendarConfig, absences map[string][]Absence) *Calendar {
+	c := &Calendar{
+		holidays: make(map[string]string),
+		absences: make(map[string][]Absence),
+	}
+
+	workingDays := config.WorkingDays
+	if len(workingDays) == 0 {
+		workingDays = DefaultWorkingDays
+	}
+	for _, d := range workingDays {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if len(d) > 3 {
+			d = d[0:3]
+		}
+		if weekday, found := weekdays[d]; found {
+			c.workingDays[weekday] = true
+		} else {
+			logrus.Warnf("invalid working day %s in calendar", d)
+		}
+	}
+	for _, holiday := range config.Holidays {
+		c.holidays[holiday.Date] = holiday.Name
+	}
+	for user, as := range absences {
+		c.absences[normalizeUser(user)] = as
+	}
+	return c
+}
+
+// GetCalendar returns the calendar of the project with the absences of all users
+func GetCalendar(project *Project) *Calendar {
+	absences := make(map[string][]Absence)
+	for _, user := range GetUserList(project) {
+		if userInfo, err := GetUserInfo(project, user); err == nil && len(userInfo.Absences) > 0 {
+			absences[user] = userInfo.Absences
+		}
+	}
+	return NewCalendar(project.Config.Public.Calendar, absences)
+}
+
+// SetCalendar saves the calendar in the project configuration
+func SetCalendar(project *Project, config CalendarConfig) error {
+	sort.Slice(config.Holidays, func(i, j int) bool {
+		return config.Holidays[i].Date < config.Holidays[j].Date
+	})
+	project.ConfigMutex.Lock()
+	defer project.ConfigMutex.Unlock()
+
+	project.Config.Public.Calendar = config
+	return WriteProjectConfig(project.Path, &project.Config)
+}
+
+// IsHoliday returns the name of the holiday on the given day
+func (c *Calendar) IsHoliday(day time.Time) (string, bool) {
+	name, found := c.holidays[day.Format(DateLayout)]
+	return name, found
+}
+
+// IsAbsent returns true when the user is on leave on the given day
+func (c *Calendar) IsAbsent(user string, day time.Time) bool {
+	d := day.Format(DateLayout)
+	for _, absence := range c.absences[normalizeUser(user)] {
+		if d >= absence.From && d <= absence.To {
+			return true
+		}
+	}
+	return false
+}
+
+// IsWorkingDay returns true when the user works on the given day. An empty user checks only
+// the project calendar.
+func (c *Calendar) IsWorkingDay(user string, day time.Time) bool {
+	if !c.workingDays[day.Weekday()] {
+		return false
+	}
+	if _, found := c.IsHoliday(day); found {
+		return false
+	}
+	return user == "" || !c.IsAbsent(user, day)
+}
+
+// WorkingDays counts the days from (included) to (excluded) when the user works
+func (c *Calendar) WorkingDays(user string, from time.Time, to time.Time) int {
+	cnt := 0
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if c.IsWorkingDay(user, day) {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// Capacity returns the working days of each user from (included) to (excluded), e.g. for a sprint
+func (c *Calendar) Capacity(users []string, from time.Time, to time.Time) map[string]int {
+	capacity := make(map[string]int, len(users))
+	for _, user := range users {
+		capacity[user] = c.WorkingDays(user, from, to)
+	}
+	return capacity
+}
+
+// IdealBurndown returns the ideal remaining work at the start of each day from (included) to (included),
+// so that the last point is after all the working days from (included) to (excluded). The work
+// decreases only on working days of the project.
+func (c *Calendar) IdealBurndown(total float64, from time.Time, to time.Time) []BurndownPoint {
+	var points []BurndownPoint
+	days := c.WorkingDays("", from, to)
+	remaining := total
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		points = append(points, BurndownPoint{Date: day.Format(DateLayout), Remaining: remaining})
+		if days > 0 && c.IsWorkingDay("", day) {
+			remaining -= total / float64(days)
+			if remaining < 1e-9 {
+				remaining = 0
+			}
+		}
+	}
+	return points
+}
+
+// unfoldICS joins the continuation lines of an iCalendar file
+func unfoldICS(data []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+		} else {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func parseICSDate(value string) (time.Time, bool) {
+	for _, layout := range []string{"20060102", "20060102T150405Z", "20060102T150405"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseICS reads the events of an iCalendar file as holidays. Events longer than a day
+// become a holiday for each day.
+func ParseICS(data []byte) ([]Holiday, error) {
+	var holidays []Holiday
+	var start, end time.Time
+	var summary string
+	inEvent := false
+
+	for _, line := range unfoldICS(data) {
+		idx := strings.IndexByte(line, ':')
+		if idx < 0 {
+			continue
+		}
+		name, value := line[0:idx], strings.TrimSpace(line[idx+1:])
+		if semicolon := strings.IndexByte(name, ';'); semicolon >= 0 {
+			name = name[0:semicolon]
+		}
+
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if value == "VEVENT" {
+				inEvent, start, end, summary = true, time.Time{}, time.Time{}, ""
+			}
+		case "DTSTART":
+			start, _ = parseICSDate(value)
+		case "DTEND":
+			end, _ = parseICSDate(value)
+		case "SUMMARY":
+			summary = strings.ReplaceAll(value, "\\,", ",")
+		case "END":
+			if value != "VEVENT" || !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				return nil, ErrInvalidCalendar
+			}
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+				holidays = append(holidays, Holiday{Date: day.Format(DateLayout), Name: summary})
+			}
+		}
+	}
+	if inEvent {
+		return nil, ErrInvalidCalendar
+	}
+	return holidays, nil
+}
+
+// ImportICS adds the events in an iCalendar file to the holidays of the project. It returns the
+// number of holidays added.
+func ImportICS(project *Project, data []byte) (int, error) {
+	holidays, err := ParseICS(data)
+	if err != nil {
+		return 0, err
+	}
+
+	config := project.Config.Public.Calendar
+	existing := make(map[string]bool)
+	for _, holiday := range config.Holidays {
+		existing[holiday.Date] = true
+	}
+	added := 0
+	for _, holiday := range holidays {
+		if !existing[holiday.Date] {
+			config.Holidays = append(config.Holidays, holiday)
+			existing[holiday.Date] = true
+			added++
+		}
+	}
+	logrus.Infof("Imported %d holidays in project %s", added, project.Config.Public.Name)
+	return added, SetCalendar(project, config)
+}
+
+// SetAbsences replaces the absences of a user. The user can have the @ prefix, e.g. @bob.
+func SetAbsences(project *Project, user string, absences []Absence) error {
+	for _, absence := range absences {
+		from, err1 := time.Parse(DateLayout, absence.From)
+		to, err2 := time.Parse(DateLayout, absence.To)
+		if err1 != nil || err2 != nil || to.Before(from) {
+			return ErrInvalidCalendar
+		}
+	}
+
+	// user files keep the case of the name, while calendar lookups ignore it
+	user = strings.TrimPrefix(user, "@")
+	userInfo, err := GetUserInfo(project, user)
+	if err != nil {
+		return err
+	}
+	userInfo.Absences = absences
+	return SetUserInfo(project, user, &userInfo)
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendarWorkingDays(t *testing.T) {
+	calendar := NewCalendar(CalendarConfig{
+		Holidays: []Holiday{{Date: "2021-06-02", Name: "Festa della Repubblica"}},
+	}, map[string][]Absence{
+		"bob": {{From: "2021-06-03", To: "2021-06-04"}},
+	})
+
+	from := time.Date(2021, 5, 31, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	assert.Equal(t, 4, calendar.WorkingDays("", from, to))
+	assert.Equal(t, 2, calendar.WorkingDays("@bob", from, to))
+	assert.Equal(t, map[string]int{"bob": 2, "alice": 4}, calendar.Capacity([]string{"bob", "alice"}, from, to))
+
+	points := calendar.IdealBurndown(8, from, to)
+	assert.Len(t, points, 8)
+	assert.Equal(t, 4.0, points[2].Remaining)
+	assert.Equal(t, 4.0, points[3].Remaining)
+	assert.Equal(t, 0.0, points[7].Remaining)
+
+	weekend := NewCalendar(CalendarConfig{WorkingDays: []string{"Sunday"}}, nil)
+	assert.Equal(t, 1, weekend.WorkingDays("", from, to))
+}
+
+func TestParseICS(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20211224\r\nDTEND;VALUE=DATE:20211227\r\n" +
+		"SUMMARY:Christmas\r\n  holidays\r\nEND:VEVENT\r\nBEGIN:VEVENT\r\nDTSTART:20220101\r\nSUMMARY:New year\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	holidays, err := ParseICS([]byte(ics))
+	assert.Nil(t, err)
+	assert.Equal(t, []Holiday{
+		{Date: "2021-12-24", Name: "Christmas holidays"},
+		{Date: "2021-12-25", Name: "Christmas holidays"},
+		{Date: "2021-12-26", Name: "Christmas holidays"},
+		{Date: "2022-01-01", Name: "New year"},
+	}, holidays)
+
+	_, err = ParseICS([]byte("BEGIN:VEVENT\nSUMMARY:Broken\nEND:VEVENT\n"))
+	assert.Equal(t, ErrInvalidCalendar, err)
+}
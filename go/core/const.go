@@ -29,6 +29,15 @@ const ProjectArchiveFolder = "archive"
 
 const ProjectChatFolder = "chat"
 
+// ProjectTrashFolder the folder containing the items deleted from the library
+const ProjectTrashFolder = "trash"
+
+// ProjectBlobsFolder the folder containing the content of library and chat files, named by hash
+const ProjectBlobsFolder = "blobs"
+
+// ProjectBaselinesFolder the folder containing the gantt baselines
+const ProjectBaselinesFolder = "baselines"
+
 
 // ProjectUsersFolder the folder containing users
 const ProjectModelsFolder = "models"
@@ -50,7 +59,8 @@ var (
 	ProjectFolders = []string{ProjectBoardsFolder,
 		ProjectLibraryFolder, ProjectUsersFolder,
 		ProjectLibraryInlineImagesFolder, ProjectModelsFolder,
-		ProjectFedFolder, ProjectFedFilesFolder, ProjectChatFolder}
+		ProjectFedFolder, ProjectFedFilesFolder, ProjectChatFolder,
+		ProjectBaselinesFolder, ProjectTrashFolder, ProjectBlobsFolder}
 
 	ProjectTemplatesPath = "assets/templates/"
 
@@ -0,0 +1,322 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// MaxExtractedText limits the text extracted from a single document
+const MaxExtractedText = 4 << 20
+
+// ExtractText returns the plain text of a library document. Markdown and text files are returned as
+// they are; HTML, PDF and DOCX files are converted. The extraction of PDF is best effort: only text
+// in uncompressed or Flate compressed content streams with simple fonts is found.
+func ExtractText(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var text []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".txt":
+		text = data
+	case ".html", ".htm":
+		text = extractHTML(data)
+	case ".pdf":
+		text = extractPDF(data)
+	case ".docx":
+		if text, err = extractDOCX(data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidType
+	}
+	if len(text) > MaxExtractedText {
+		text = text[0:MaxExtractedText]
+	}
+	return text, nil
+}
+
+var htmlBlocks = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "section": true, "article": true, "pre": true, "blockquote": true,
+}
+
+func extractHTML(data []byte) []byte {
+	var text bytes.Buffer
+	skip := 0
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return text.Bytes()
+		case html.TextToken:
+			if skip == 0 {
+				text.Write(tokenizer.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			switch tag := string(name); {
+			case tag == "script" || tag == "style":
+				skip++
+			case htmlBlocks[tag]:
+				text.WriteByte('\n')
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch tag := string(name); {
+			case (tag == "script" || tag == "style") && skip > 0:
+				skip--
+			case htmlBlocks[tag]:
+				text.WriteByte('\n')
+			}
+		}
+	}
+}
+
+// extractDOCX reads the paragraphs in the main document of a Word file
+func extractDOCX(data []byte) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var text bytes.Buffer
+	for _, file := range archive.File {
+		if file.Name != "word/document.xml" {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		inText := false
+		decoder := xml.NewDecoder(io.LimitReader(r, 8*MaxExtractedText))
+		for {
+			token, err := decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				switch t.Name.Local {
+				case "t":
+					inText = true
+				case "tab":
+					text.WriteByte('\t')
+				case "br", "cr":
+					text.WriteByte('\n')
+				}
+			case xml.EndElement:
+				switch t.Name.Local {
+				case "t":
+					inText = false
+				case "p":
+					text.WriteByte('\n')
+				}
+			case xml.CharData:
+				if inText {
+					text.Write(t)
+				}
+			}
+		}
+	}
+	return text.Bytes(), nil
+}
+
+var (
+	pdfFlate  = regexp.MustCompile(`/FlateDecode`)
+	pdfBinary = regexp.MustCompile(`/Subtype\s*/Image|/Length1|/Type\s*/XRef|/Type\s*/ObjStm`)
+)
+
+// extractPDF finds the text shown by the operators Tj, TJ, ' and " in the content streams
+func extractPDF(data []byte) []byte {
+	var text bytes.Buffer
+	for pos := 0; text.Len() < MaxExtractedText; {
+		idx := bytes.Index(data[pos:], []byte("stream"))
+		if idx < 0 {
+			break
+		}
+		keyword := pos + idx
+		pos = keyword + len("stream")
+		if !bytes.HasSuffix(bytes.TrimRight(data[:keyword], " \t\r\n"), []byte(">>")) {
+			continue
+		}
+
+		// the dictionary of the stream is between the object header and the keyword
+		from := keyword - 2048
+		if from < 0 {
+			from = 0
+		}
+		if obj := bytes.LastIndex(data[from:keyword], []byte("obj")); obj >= 0 {
+			from += obj
+		}
+		dict := data[from:keyword]
+
+		start := pos
+		if start < len(data) && data[start] == '\r' {
+			start++
+		}
+		if start < len(data) && data[start] == '\n' {
+			start++
+		}
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		pos = start + end + len("endstream")
+		if pdfBinary.Match(dict) {
+			continue
+		}
+
+		content := data[start : start+end]
+		if pdfFlate.Match(dict) {
+			r, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				continue
+			}
+			content, _ = ioutil.ReadAll(io.LimitReader(r, 8*MaxExtractedText))
+			_ = r.Close()
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+		extractPDFContent(content, &text)
+	}
+	return text.Bytes()
+}
+
+// extractPDFContent interprets the text operators of a content stream
+func extractPDFContent(content []byte, text *bytes.Buffer) {
+	var strs [][]byte
+	inText := false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, next := readPDFString(content, i)
+			strs = append(strs, s)
+			i = next
+		case c == '[' || c == ']':
+		case c == '-' || (c >= '0' && c <= '9') || c == '.':
+			j := i
+			for j < len(content) && (content[j] == '-' || content[j] == '.' || (content[j] >= '0' && content[j] <= '9')) {
+				j++
+			}
+			// a large negative adjustment in TJ arrays is a space between words
+			if len(strs) > 0 && content[i] == '-' && j-i > 3 {
+				strs = append(strs, []byte(" "))
+			}
+			i = j - 1
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c >= 'A' && c <= 'z' || c == '\'' || c == '"' || c == '*':
+			j := i
+			for j < len(content) && (content[j] >= 'A' && content[j] <= 'z' || content[j] == '\'' ||
+				content[j] == '"' || content[j] == '*') {
+				j++
+			}
+			switch string(content[i:j]) {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				text.WriteByte('\n')
+			case "Tj", "TJ":
+				if inText {
+					writePDFStrings(strs, text)
+				}
+			case "'", "\"":
+				if inText {
+					text.WriteByte('\n')
+					writePDFStrings(strs, text)
+				}
+			case "T*", "Td", "TD":
+				if inText {
+					text.WriteByte('\n')
+				}
+			}
+			strs = strs[:0]
+			i = j - 1
+		}
+	}
+}
+
+func writePDFStrings(strs [][]byte, text *bytes.Buffer) {
+	for _, s := range strs {
+		// strings are decoded as Latin-1, which covers WinAnsi and standard encodings for letters
+		for _, b := range s {
+			if r := rune(b); unicode.IsPrint(r) || r == ' ' {
+				var buf [utf8.UTFMax]byte
+				text.Write(buf[:utf8.EncodeRune(buf[:], r)])
+			}
+		}
+	}
+}
+
+// readPDFString reads a literal string starting at the open parenthesis. It returns the string and
+// the position of the closing parenthesis.
+func readPDFString(content []byte, start int) ([]byte, int) {
+	var s []byte
+	depth := 0
+	for i := start; i < len(content); i++ {
+		c := content[i]
+		switch c {
+		case '(':
+			if depth > 0 {
+				s = append(s, c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s, i
+			}
+			s = append(s, c)
+		case '\\':
+			if i+1 >= len(content) {
+				return s, i
+			}
+			i++
+			switch e := content[i]; e {
+			case 'n', 'r':
+				s = append(s, ' ')
+			case 't':
+				s = append(s, '\t')
+			case 'b', 'f':
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				v := 0
+				for j := 0; j < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; j++ {
+					v = v*8 + int(content[i]-'0')
+					i++
+				}
+				i--
+				s = append(s, byte(v))
+			case '\r', '\n':
+			default:
+				s = append(s, e)
+			}
+		default:
+			s = append(s, c)
+		}
+	}
+	return s, len(content)
+}
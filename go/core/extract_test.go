@@ -0,0 +1,56 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractHTML(t *testing.T) {
+	text := extractHTML([]byte(`<html><head><style>p {color: red}</style></head>` +
+		`<body><h1>Token</h1><p>The token refresh &amp; expiry</p><script>var x = 1</script></body></html>`))
+	assert.Equal(t, "\nToken\n\nThe token refresh & expiry\n", string(text))
+}
+
+func TestExtractDOCX(t *testing.T) {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	f, _ := w.Create("word/document.xml")
+	_, _ = f.Write([]byte(`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+		`<w:body><w:p><w:r><w:t>Token</w:t></w:r><w:r><w:t xml:space="preserve"> refresh</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>Second</w:t></w:r></w:p></w:body></w:document>`))
+	_ = w.Close()
+
+	text, err := extractDOCX(b.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "Token refresh\nSecond\n", string(text))
+}
+
+func TestExtractPDF(t *testing.T) {
+	var content bytes.Buffer
+	z := zlib.NewWriter(&content)
+	_, _ = z.Write([]byte("BT /F1 12 Tf 72 712 Td (Token refresh) Tj T* [(in) -250 (\\(PDF\\))] TJ ET"))
+	_ = z.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Font /Subtype /Type1 >>\nendobj\n")
+	_, _ = fmt.Fprintf(&pdf, "2 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", content.Len())
+	pdf.Write(content.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+
+	folder, _ := ioutil.TempDir("", "extract")
+	defer os.RemoveAll(folder)
+	p := filepath.Join(folder, "spec.pdf")
+	_ = ioutil.WriteFile(p, pdf.Bytes(), 0644)
+
+	text, err := ExtractText(p)
+	assert.Nil(t, err)
+	assert.Equal(t, "\nToken refresh\nin (PDF)\n", string(text))
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+// ViewBoardPrefix is the prefix of virtual boards created from shared filters
+const ViewBoardPrefix = "view:"
+
+// Filter is a named query in the textual query language, e.g. type:bug status:!#Done
+type Filter struct {
+	Name  string `json:"name" yaml:"name"`
+	Query string `json:"query" yaml:"query"`
+}
+
+// Filters contains the personal filters of a user and the filters shared in the project
+type Filters struct {
+	Personal []Filter `json:"personal"`
+	Shared   []Filter `json:"shared"`
+}
+
+func findFilter(filters []Filter, name string) (int, bool) {
+	for i, filter := range filters {
+		if filter.Name == name {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// ListFilters returns the personal filters of the user and the shared filters of the project
+func ListFilters(project *Project, user string) Filters {
+	filters := Filters{
+		Personal: []Filter{},
+		Shared:   []Filter{},
+	}
+
+	if userInfo, err := GetUserInfo(project, user); err == nil && userInfo.Filters != nil {
+		filters.Personal = userInfo.Filters
+	}
+	filters.Shared = append(filters.Shared, getSharedFilters(project)...)
+	return filters
+}
+
+// getSharedFilters returns a copy of the shared filters, since the configuration may change meanwhile
+func getSharedFilters(project *Project) []Filter {
+	project.ConfigMutex.Lock()
+	defer project.ConfigMutex.Unlock()
+
+	return append([]Filter{}, project.Config.Public.Filters...)
+}
+
+// GetSharedFilter looks for a shared filter with the given name, ignoring the personal filters of users
+func GetSharedFilter(project *Project, name string) (Filter, error) {
+	filters := getSharedFilters(project)
+	if idx, found := findFilter(filters, name); found {
+		return filters[idx], nil
+	}
+	return Filter{}, ErrNoFound
+}
+
+// GetFilter looks for a filter with the given name. Personal filters hide shared filters with the same name.
+func GetFilter(project *Project, user string, name string) (Filter, error) {
+	filters := ListFilters(project, user)
+	if idx, found := findFilter(filters.Personal, name); found {
+		return filters.Personal[idx], nil
+	}
+	if idx, found := findFilter(filters.Shared, name); found {
+		return filters.Shared[idx], nil
+	}
+	return Filter{}, ErrNoFound
+}
+
+// SetFilter creates or replaces a filter in the user file or, when shared, in the project configuration
+func SetFilter(project *Project, user string, filter Filter, shared bool) error {
+	if shared {
+		project.ConfigMutex.Lock()
+		defer project.ConfigMutex.Unlock()
+
+		filters := project.Config.Public.Filters
+		if idx, found := findFilter(filters, filter.Name); found {
+			filters[idx] = filter
+		} else {
+			filters = append(filters, filter)
+		}
+		project.Config.Public.Filters = filters
+		logrus.Infof("Shared filter %s set to '%s'", filter.Name, filter.Query)
+		return WriteProjectConfig(project.Path, &project.Config)
+	}
+
+	userInfo, err := GetUserInfo(project, user)
+	if err != nil {
+		return err
+	}
+	if idx, found := findFilter(userInfo.Filters, filter.Name); found {
+		userInfo.Filters[idx] = filter
+	} else {
+		userInfo.Filters = append(userInfo.Filters, filter)
+	}
+	logrus.Infof("Filter %s of user %s set to '%s'", filter.Name, user, filter.Query)
+	return SetUserInfo(project, user, &userInfo)
+}
+
+// DeleteFilter removes a personal or shared filter
+func DeleteFilter(project *Project, user string, name string, shared bool) error {
+	if shared {
+		project.ConfigMutex.Lock()
+		defer project.ConfigMutex.Unlock()
+
+		filters := project.Config.Public.Filters
+		idx, found := findFilter(filters, name)
+		if !found {
+			return ErrNoFound
+		}
+		project.Config.Public.Filters = append(append([]Filter{}, filters[0:idx]...), filters[idx+1:]...)
+		return WriteProjectConfig(project.Path, &project.Config)
+	}
+
+	userInfo, err := GetUserInfo(project, user)
+	if err != nil {
+		return err
+	}
+	idx, found := findFilter(userInfo.Filters, name)
+	if !found {
+		return ErrNoFound
+	}
+	userInfo.Filters = append(userInfo.Filters[0:idx], userInfo.Filters[idx+1:]...)
+	return SetUserInfo(project, user, &userInfo)
+}
+
+// ListViewBoards returns the virtual boards for the shared filters in the project
+func ListViewBoards(project *Project) []string {
+	filters := getSharedFilters(project)
+	views := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		views = append(views, ViewBoardPrefix+filter.Name)
+	}
+	return views
+}
+
+// IsViewBoard returns true when the board is a virtual board for a shared filter
+func IsViewBoard(board string) bool {
+	return strings.HasPrefix(board, ViewBoardPrefix)
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedFilters(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "ash-filters")
+	defer os.RemoveAll(dir)
+
+	project := &Project{Path: dir}
+	assert.Nil(t, SetFilter(project, "alice", Filter{Name: "bugs", Query: "type:bug"}, true))
+	assert.Nil(t, SetFilter(project, "alice", Filter{Name: "bugs", Query: "type:bug status:!#Done"}, true))
+	assert.Equal(t, []string{ViewBoardPrefix + "bugs"}, ListViewBoards(project))
+
+	filter, err := GetSharedFilter(project, "bugs")
+	assert.Nil(t, err)
+	assert.Equal(t, "type:bug status:!#Done", filter.Query)
+
+	config, err := ReadProjectConfig(dir)
+	assert.Nil(t, err)
+	assert.Len(t, config.Public.Filters, 1)
+
+	assert.Nil(t, DeleteFilter(project, "alice", "bugs", true))
+	assert.Equal(t, ErrNoFound, DeleteFilter(project, "alice", "bugs", true))
+	_, err = GetSharedFilter(project, "bugs")
+	assert.Equal(t, ErrNoFound, err)
+}
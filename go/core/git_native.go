@@ -58,7 +58,7 @@ func (client Native) GetStatus(project *Project) (GitStatus, error) {
 
 		if parts[0] == ProjectFolder {
 			switch parts[1] {
-			case ProjectBoardsFolder, ProjectConfigFile, ProjectUsersFolder:
+			case ProjectBoardsFolder, ProjectBaselinesFolder, ProjectConfigFile, ProjectUsersFolder:
 				gitStatus.AshFiles = append(gitStatus.AshFiles, name)
 				logrus.Debugf("Add file '%s' to Git status", name)
 			case ProjectLibraryFolder:
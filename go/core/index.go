@@ -4,7 +4,6 @@ import (
 	"almost-scrum/fs"
 	"github.com/monirz/gotri"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/text/unicode/norm"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -16,7 +15,6 @@ import (
 var (
 	wordSegment = regexp.MustCompile(`[#@]?[\pL\p{Mc}\p{Mn}][\pL\p{Mc}\p{Mn}\p{N}_']*`)
 	//	wordSegment = regexp.MustCompile(`([^\n][#@])?[\pNL\p{Mc}\p{Mn}_']+`)
-	stopWords = english
 )
 
 // TagLink is a link to a story
@@ -32,8 +30,11 @@ type TagLinks []TagLink
 type Ids []uint16
 
 type Index struct {
-	StopWords  []string       `json:"stop_words"`
-	Ids        map[string]Ids `json:"ids"`
+	Analyzer   string              `json:"analyzer"`
+	StopWords  []string            `json:"stop_words"`
+	Ids        map[string]Ids      `json:"ids"`
+	Forms      map[string]string   `json:"forms"`
+	Sources    map[string][]string `json:"sources"`
 	searchTree *gotri.Trie
 	modTime    time.Time
 }
@@ -71,7 +72,47 @@ func SearchTask(project *Project, board string, matchAll bool, keys ...string) (
 	return infos[0:l], nil
 }
 
+// rLockIndex takes the read lock of the index, reading the index first when it is not loaded.
+// The caller must release the lock with RUnlock and must not take the lock again in the meantime.
+func rLockIndex(project *Project) error {
+	for {
+		project.IndexMutex.RLock()
+		if project.Index != nil {
+			return nil
+		}
+		project.IndexMutex.RUnlock()
+
+		project.IndexMutex.Lock()
+		var err error
+		if project.Index == nil {
+			err = ReadIndex(project)
+		}
+		project.IndexMutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// IndexedKeys returns the keys that produce index keys, i.e. without the stop words
+func IndexedKeys(project *Project, keys []string) []string {
+	project.IndexMutex.RLock()
+	defer project.IndexMutex.RUnlock()
+
+	indexed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if len(project.Analyzer.Keys(key)) > 0 {
+			indexed = append(indexed, key)
+		}
+	}
+	return indexed
+}
+
 func SuggestKeys(project *Project, prefix string, total int) []string {
+	if err := rLockIndex(project); IsErr(err, "cannot read index for %s", project.Path) {
+		return []string{}
+	}
+	defer project.IndexMutex.RUnlock()
 	suggestions := project.Index.searchTree.GetSuggestion(prefix, total)
 	if suggestions == nil {
 		return []string{}
@@ -92,23 +133,22 @@ func SuggestKeys(project *Project, prefix string, total int) []string {
 //}
 
 func lookupTaskIds(project *Project, keys ...string) (map[uint16]int, error) {
-	if project.Index == nil {
-		if err := ReadIndex(project); IsErr(err, "cannot read index for %s", project.Path) {
-			return map[uint16]int{}, err
-		}
+	if err := rLockIndex(project); IsErr(err, "cannot read index for %s", project.Path) {
+		return map[uint16]int{}, err
 	}
+	defer project.IndexMutex.RUnlock()
 
 	idsSet := make(map[uint16]int)
 	for _, key := range keys {
-		if !strings.HasPrefix(key, "@") && !strings.HasPrefix(key, "#") {
-			key = strings.ToLower(key)
-		}
-		ids, ok := project.Index.Ids[key]
-		if ok {
-			for _, id := range ids {
-				idsSet[id] += 1
+		matches := make(map[uint16]bool)
+		for _, k := range project.Analyzer.Keys(key) {
+			for _, id := range project.Index.Ids[k] {
+				matches[id] = true
 			}
 		}
+		for id := range matches {
+			idsSet[id] += 1
+		}
 	}
 	return idsSet, nil
 }
@@ -122,7 +162,9 @@ func ClearIndex(project *Project) error {
 	if err != nil {
 		return err
 	}
+	project.IndexMutex.Lock()
 	project.Index = nil
+	project.IndexMutex.Unlock()
 	return os.Remove(p)
 }
 
@@ -160,7 +202,10 @@ func indexTask(project *Project, info TaskInfo, newStopWords *[]string) {
 		idsLimit = 10
 	}
 
-	normal, special := getWordsInTask(project, info.Board, info.Name)
+	normal, special, forms := getWordsInTask(project, info.Board, info.Name)
+	for form, key := range forms {
+		project.Index.Forms[form] = key
+	}
 	clearIndex(info.ID, project.Index)
 	mergeToIndex(info.ID, normal, project.Index, idsLimit, newStopWords)
 	mergeToIndex(info.ID, special, project.Index, -1, nil)
@@ -208,7 +253,7 @@ func ReIndex(project *Project) error {
 		}
 	}
 
-	mergeStopWords(project.Index)
+	project.Analyzer.AddStopWords(project.Index.StopWords)
 	newStopWords := make([]string, 0)
 
 	infos, err := ListTasks(project, "", "")
@@ -221,6 +266,7 @@ func ReIndex(project *Project) error {
 			indexTask(project, info, &newStopWords)
 		}
 	}
+	reIndexSources(project)
 
 	logrus.Debugf("New stop words: %v", newStopWords)
 	for _, word := range newStopWords {
@@ -236,6 +282,51 @@ func ReIndex(project *Project) error {
 	return WriteIndex(project)
 }
 
+// ReIndexTasks updates the index only for the provided changes. A task moved between boards
+// appears as a deletion and a creation with the same id, so deletions are applied first.
+// Sources are documents and messages, identified by their path relative to the project.
+func ReIndexTasks(project *Project, changes []TaskChange, sources ...string) error {
+	project.IndexMutex.Lock()
+	defer project.IndexMutex.Unlock()
+
+	if project.Index == nil {
+		if err := ReadIndex(project); err != nil {
+			return err
+		}
+	}
+
+	project.Analyzer.AddStopWords(project.Index.StopWords)
+	newStopWords := make([]string, 0)
+
+	for _, change := range changes {
+		if change.Deleted {
+			id, _ := ExtractTaskId(change.Name)
+			clearIndex(id, project.Index)
+		}
+	}
+	for _, change := range changes {
+		if !change.Deleted {
+			id, _ := ExtractTaskId(change.Name)
+			if id == 0 {
+				continue
+			}
+			indexTask(project, TaskInfo{ID: id, Board: change.Board, Name: change.Name}, &newStopWords)
+		}
+	}
+	for _, source := range sources {
+		reIndexSource(project, source)
+	}
+
+	for _, word := range newStopWords {
+		delete(project.Index.Ids, word)
+	}
+	project.Index.StopWords = append(project.Index.StopWords, newStopWords...)
+
+	BuiltSearchTree(project)
+	project.Index.modTime = time.Now()
+	return WriteIndex(project)
+}
+
 func clearIndex(id uint16, index *Index) {
 	for key, ids := range index.Ids {
 		l := len(ids)
@@ -287,47 +378,24 @@ func isMissing(id uint16, ids Ids) bool {
 	return true
 }
 
-func mergeStopWords(index *Index) {
-	for _, word := range index.StopWords {
-		stopWords[word] = ""
-	}
-}
-
-func getWordsInTask(project *Project, board string, name string) (normal []string, special []string) {
+func getWordsInTask(project *Project, board string, name string) (normal []string, special []string,
+	forms map[string]string) {
 	p := filepath.Join(project.Path, "boards", board, name+TaskFileExt)
 	data, err := ioutil.ReadFile(p)
 	if err != nil {
 		logrus.Errorf("Cannot read task file %s: %v", p, err)
-		return []string{}, []string{}
+		return []string{}, []string{}, map[string]string{}
 	}
 	_, title := ExtractTaskId(name)
+	data = append(data, ' ')
 	data = append(data, []byte(title)...)
 
-	normal, special = cleanText(data)
+	normal, special, forms = project.Analyzer.Analyze(data)
 	logrus.Debugf("Indexing %s/%s\n Normal Words: %v\n Special Words %v\n",
 		board, name, normal, special)
 	return
 }
 
-func cleanText(text []byte) (normal []string, special []string) {
-	normal = make([]string, 0)
-	special = make([]string, 0)
-
-	text = norm.NFC.Bytes(text)
-	words := wordSegment.FindAll(text, -1)
-	for _, w := range words {
-		s := string(w)
-		if _, found := stopWords[s]; !found {
-			if s[0] == '@' || s[0] == '#' {
-				special = append(special, s)
-			} else {
-				normal = append(normal, strings.ToLower(s))
-			}
-		}
-	}
-	return normal, special
-}
-
 func UpdateSearchTree(index *Index, ids []string) {
 	for _, k := range ids {
 		index.searchTree.Add(k, k)
@@ -336,9 +404,17 @@ func UpdateSearchTree(index *Index, ids []string) {
 }
 
 func BuiltSearchTree(project *Project) {
+	for form, key := range project.Index.Forms {
+		if _, found := project.Index.Ids[key]; found {
+			project.Index.searchTree.Add(form, form)
+			logrus.Debugf("Add form '%s' to index search tree", form)
+		}
+	}
 	for k := range project.Index.Ids {
-		project.Index.searchTree.Add(k, k)
-		logrus.Debugf("Add key '%s' to index search tree", k)
+		if strings.HasPrefix(k, "@") || strings.HasPrefix(k, "#") {
+			project.Index.searchTree.Add(k, k)
+			logrus.Debugf("Add key '%s' to index search tree", k)
+		}
 	}
 
 	for _, model := range project.Models {
@@ -353,16 +429,23 @@ func BuiltSearchTree(project *Project) {
 
 }
 
+func newIndex(project *Project) *Index {
+	return &Index{
+		Analyzer:   project.Analyzer.Signature(),
+		StopWords:  make([]string, 0),
+		Ids:        make(map[string]Ids),
+		Forms:      make(map[string]string),
+		Sources:    make(map[string][]string),
+		searchTree: new(gotri.Trie),
+		modTime:    time.Time{},
+	}
+}
+
 func ReadIndex(project *Project) error {
 	p := filepath.Join(project.Path, IndexFile)
 	info, err := os.Stat(p)
 	if os.IsNotExist(err) {
-		project.Index = &Index{
-			StopWords:  make([]string, 0),
-			Ids:        make(map[string]Ids),
-			searchTree: new(gotri.Trie),
-			modTime:    time.Time{},
-		}
+		project.Index = newIndex(project)
 		return nil
 	} else if err != nil {
 		return err
@@ -374,6 +457,13 @@ func ReadIndex(project *Project) error {
 	if err = fs.ReadJSON(p, project.Index); err != nil {
 		return err
 	}
+	if project.Index.Analyzer != project.Analyzer.Signature() || project.Index.Forms == nil ||
+		project.Index.Sources == nil {
+		logrus.Infof("Index in %s was built with analyzer '%s' and must be rebuilt with '%s'",
+			project.Path, project.Index.Analyzer, project.Analyzer.Signature())
+		project.Index = newIndex(project)
+		return nil
+	}
 
 	BuiltSearchTree(project)
 	return nil
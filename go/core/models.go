@@ -11,11 +11,14 @@ import (
 type PropertyKind string
 
 const (
-	KindString PropertyKind = "String"
-	KindEnum   PropertyKind = "Enum"
-	KindBool   PropertyKind = "Bool"
-	KindUser   PropertyKind = "User"
-	KindTag    PropertyKind = "Tag"
+	KindString     PropertyKind = "String"
+	KindEnum       PropertyKind = "Enum"
+	KindBool       PropertyKind = "Bool"
+	KindUser       PropertyKind = "User"
+	KindTag        PropertyKind = "Tag"
+	KindDate       PropertyKind = "Date"
+	KindInt        PropertyKind = "Int"
+	KindPercentage PropertyKind = "Percentage"
 )
 
 type PropertyDef struct {
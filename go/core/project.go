@@ -12,13 +12,22 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 type FileAttr struct {
-	Owner  string `json:"owner"`
-	Public bool   `json:"public_"`
+	Owner  string    `json:"owner"`
+	Public bool      `json:"public_"`
+	Lock   *FileLock `json:"lock,omitempty"`
+}
+
+// FileLock is the check-out of a library document by a user. The lock is not valid after Expires.
+type FileLock struct {
+	User    string    `json:"user"`
+	Since   time.Time `json:"since"`
+	Expires time.Time `json:"expires"`
 }
 
 
@@ -28,10 +37,14 @@ type Project struct {
 	Config ProjectConfig
 	Models []Model
 	//	EncryptionSeed string
-	Index      *Index
-	IndexMutex sync.Mutex
-	TasksCount int
-	Fed        fed.Connection
+	Analyzer     *Analyzer
+	Index        *Index
+	IndexMutex   sync.RWMutex
+	TasksCount   int
+	Fed          fed.Connection
+	Watcher      *Watcher
+	WatcherMutex sync.Mutex
+	ConfigMutex  sync.Mutex
 }
 
 // LoadTheProjectConfig
@@ -72,6 +85,7 @@ func getFedConnection(path string, fedId string) (fed.Connection, error) {
 	f.Mount(filepath.Join(path, ProjectBoardsFolder), fed.TrackByDefault)
 	f.Mount(filepath.Join(path, ProjectModelsFolder), fed.TrackByDefault)
 	f.Mount(filepath.Join(path, ProjectChatFolder), fed.TrackByDefault)
+	f.Mount(filepath.Join(path, ProjectBaselinesFolder), fed.TrackByDefault)
 	f.Mount(filepath.Join(path, ProjectLibraryFolder))
 	return f, nil
 }
@@ -100,6 +114,7 @@ func OpenProject(path string) (*Project, error) {
 		Models:     models,
 		Fed:        fedConnection,
 	}
+	SetAnalyzer(project)
 
 	infos, err := ListTasks(project, "", "")
 	if err != nil {
@@ -8,6 +8,13 @@ type ProjectConfigPublic struct {
 	BoardTypes      map[string][]string `json:"boardTypes" yaml:"boardTypes"`
 	IncludeLibInGit bool                `json:"includeLibInGit" yaml:"includeLibInGit"`
 	UseGitNative    bool                `json:"useGitNative" yaml:"useGitNative"`
+	Languages       []string            `json:"languages" yaml:"languages"`
+	StopWords       []string            `json:"stopWords" yaml:"stopWords"`
+	Filters         []Filter            `json:"filters" yaml:"filters"`
+	DaysPerPoint    float64             `json:"daysPerPoint" yaml:"daysPerPoint"`
+	Calendar        CalendarConfig      `json:"calendar" yaml:"calendar"`
+	TrashRetention  int                 `json:"trashRetention" yaml:"trashRetention"`
+	Admins          []string            `json:"admins" yaml:"admins"`
 }
 
 type ProjectConfig struct {
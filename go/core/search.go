@@ -0,0 +1,298 @@
+package core
+
+import (
+	"almost-scrum/fs"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SourceTask     = "task"
+	SourceDocument = "document"
+	SourceMessage  = "message"
+)
+
+// IndexedDocumentExts are the extensions of library files whose text is added to the index
+var IndexedDocumentExts = []string{".md", ".markdown", ".txt", ".html", ".htm", ".pdf", ".docx"}
+
+// SearchResult is an item found by Search. Link is the location of the item relative to the project,
+// e.g. boards/backlog/1.Login, library/Architecture/spec.md or chat/5c0e1a2b
+type SearchResult struct {
+	Source  string    `json:"source"`
+	Title   string    `json:"title"`
+	Link    string    `json:"link"`
+	ModTime time.Time `json:"modTime"`
+	Matches int       `json:"matches"`
+}
+
+type chatText struct {
+	Id   string `json:"id"`
+	User string `json:"user"`
+	Text string `json:"text"`
+}
+
+func getSourceType(ref string) string {
+	switch {
+	case strings.HasPrefix(ref, ProjectLibraryFolder+"/"):
+		return SourceDocument
+	case strings.HasPrefix(ref, ProjectChatFolder+"/"):
+		return SourceMessage
+	default:
+		return ""
+	}
+}
+
+func isIndexedDocument(name string) bool {
+	return !strings.HasPrefix(name, ".") &&
+		HasStringInSlice(IndexedDocumentExts, strings.ToLower(filepath.Ext(name)))
+}
+
+func readSourceText(project *Project, ref string) ([]byte, error) {
+	p := filepath.Join(project.Path, filepath.FromSlash(ref))
+	switch getSourceType(ref) {
+	case SourceDocument:
+		data, err := ExtractText(p)
+		if err != nil {
+			return nil, err
+		}
+		return append(data, []byte(" "+path.Base(ref))...), nil
+	case SourceMessage:
+		var message chatText
+		if err := fs.ReadJSON(p, &message); err != nil {
+			return nil, err
+		}
+		return []byte(message.Text), nil
+	default:
+		return nil, os.ErrInvalid
+	}
+}
+
+// listSources returns the library documents and the chat messages that can be indexed with their
+// modification time
+func listSources(project *Project) map[string]time.Time {
+	sources := make(map[string]time.Time)
+
+	library := filepath.Join(project.Path, ProjectLibraryFolder)
+	_ = filepath.Walk(library, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if p != library && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isIndexedDocument(info.Name()) {
+			rel, _ := filepath.Rel(project.Path, p)
+			sources[filepath.ToSlash(rel)] = info.ModTime()
+		}
+		return nil
+	})
+
+	infos, _ := ioutil.ReadDir(filepath.Join(project.Path, ProjectChatFolder))
+	for _, info := range infos {
+		if filepath.Ext(info.Name()) == ".json" {
+			sources[path.Join(ProjectChatFolder, info.Name())] = info.ModTime()
+		}
+	}
+	return sources
+}
+
+func clearSource(ref string, index *Index) {
+	for key, refs := range index.Sources {
+		if idx, found := FindStringInSlice(refs, ref); found {
+			refs = append(refs[0:idx], refs[idx+1:]...)
+			if len(refs) == 0 {
+				delete(index.Sources, key)
+			} else {
+				index.Sources[key] = refs
+			}
+		}
+	}
+}
+
+func indexSource(project *Project, ref string) {
+	clearSource(ref, project.Index)
+
+	text, err := readSourceText(project, ref)
+	if err != nil {
+		logrus.Warnf("cannot read %s for indexing: %v", ref, err)
+		return
+	}
+
+	normal, special, forms := project.Analyzer.Analyze(text)
+	for form, key := range forms {
+		project.Index.Forms[form] = key
+	}
+	for _, key := range append(normal, special...) {
+		refs := project.Index.Sources[key]
+		if !HasStringInSlice(refs, ref) {
+			project.Index.Sources[key] = append(refs, ref)
+		}
+	}
+	logrus.Debugf("Indexed %s with %d keys", ref, len(normal)+len(special))
+}
+
+// reIndexSources updates the index for documents and messages modified after the last index update.
+// The caller must hold the index lock.
+func reIndexSources(project *Project) {
+	sources := listSources(project)
+
+	indexed := make(map[string]bool)
+	for _, refs := range project.Index.Sources {
+		for _, ref := range refs {
+			indexed[ref] = true
+		}
+	}
+	for ref := range indexed {
+		if _, found := sources[ref]; !found {
+			clearSource(ref, project.Index)
+		}
+	}
+
+	for ref, modTime := range sources {
+		if modTime.Sub(project.Index.modTime) > 0 {
+			indexSource(project, ref)
+		}
+	}
+}
+
+// reIndexSource updates the index for a single document or message. The caller must hold the index lock.
+func reIndexSource(project *Project, ref string) {
+	ref = filepath.ToSlash(ref)
+	if _, err := os.Stat(filepath.Join(project.Path, filepath.FromSlash(ref))); os.IsNotExist(err) {
+		clearSource(ref, project.Index)
+	} else {
+		indexSource(project, ref)
+	}
+}
+
+func getSourceResult(project *Project, ref string, matches int) (SearchResult, error) {
+	p := filepath.Join(project.Path, filepath.FromSlash(ref))
+	info, err := os.Stat(p)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	source := getSourceType(ref)
+	result := SearchResult{
+		Source:  source,
+		Title:   path.Base(ref),
+		Link:    ref,
+		ModTime: info.ModTime(),
+		Matches: matches,
+	}
+	if source == SourceMessage {
+		var message chatText
+		if err := fs.ReadJSON(p, &message); err != nil {
+			return SearchResult{}, err
+		}
+		title := message.Text
+		if runes := []rune(title); len(runes) > 80 {
+			title = string(runes[0:80]) + "..."
+		}
+		result.Title = title
+		result.Link = path.Join(ProjectChatFolder, message.Id)
+	}
+	return result, nil
+}
+
+// Search looks for keys in tasks, library documents and chat messages. When matchAll is true,
+// only items that contain all keys are returned. Results are sorted by number of matches and
+// modification time.
+func Search(project *Project, matchAll bool, keys ...string) ([]SearchResult, error) {
+	results := make([]SearchResult, 0)
+	keys = IndexedKeys(project, keys)
+	if len(keys) == 0 {
+		return results, nil
+	}
+
+	infos, err := SearchTask(project, "", matchAll, keys...)
+	if IsErr(err, "cannot search tasks in %s", project.Path) {
+		return results, err
+	}
+	idsSet, _ := lookupTaskIds(project, keys...)
+	for _, info := range infos {
+		results = append(results, SearchResult{
+			Source:  SourceTask,
+			Title:   info.Name,
+			Link:    path.Join(ProjectBoardsFolder, info.Board, info.Name),
+			ModTime: info.ModTime,
+			Matches: idsSet[info.ID],
+		})
+	}
+
+	results = append(results, searchSources(project, matchAll, keys...)...)
+	sortResults(results)
+	return results, nil
+}
+
+// SearchDocuments looks for keys only in the library documents
+func SearchDocuments(project *Project, matchAll bool, keys ...string) ([]SearchResult, error) {
+	results := make([]SearchResult, 0)
+	keys = IndexedKeys(project, keys)
+	if len(keys) == 0 {
+		return results, nil
+	}
+	for _, result := range searchSources(project, matchAll, keys...) {
+		if result.Source == SourceDocument {
+			results = append(results, result)
+		}
+	}
+	sortResults(results)
+	return results, nil
+}
+
+// lookupSources returns the documents and messages with the number of keys they contain
+func lookupSources(project *Project, keys ...string) map[string]int {
+	refsSet := make(map[string]int)
+	if err := rLockIndex(project); IsErr(err, "cannot read index for %s", project.Path) {
+		return refsSet
+	}
+	defer project.IndexMutex.RUnlock()
+
+	for _, key := range keys {
+		matches := make(map[string]bool)
+		for _, k := range project.Analyzer.Keys(key) {
+			for _, ref := range project.Index.Sources[k] {
+				matches[ref] = true
+			}
+		}
+		for ref := range matches {
+			refsSet[ref] += 1
+		}
+	}
+	return refsSet
+}
+
+func searchSources(project *Project, matchAll bool, keys ...string) []SearchResult {
+	var results []SearchResult
+	for ref, cnt := range lookupSources(project, keys...) {
+		if matchAll && cnt < len(keys) {
+			continue
+		}
+		result, err := getSourceResult(project, ref, cnt)
+		if err != nil {
+			logrus.Warnf("cannot get search result for %s: %v", ref, err)
+			continue
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func sortResults(results []SearchResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Matches != results[j].Matches {
+			return results[i].Matches > results[j].Matches
+		}
+		return results[i].ModTime.After(results[j].ModTime)
+	})
+}
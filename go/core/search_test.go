@@ -0,0 +1,48 @@
+package core
+
+import (
+	"almost-scrum/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageTitle(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "ash-search")
+	defer os.RemoveAll(dir)
+
+	project := &Project{Path: dir}
+	_ = os.MkdirAll(filepath.Join(dir, ProjectChatFolder), 0755)
+	text := strings.Repeat("è", 100)
+	_ = fs.WriteJSON(filepath.Join(dir, ProjectChatFolder, "1.json"), chatText{Id: "1", Text: text})
+
+	result, err := getSourceResult(project, ProjectChatFolder+"/1.json", 1)
+	assert.Nil(t, err)
+	assert.True(t, utf8.ValidString(result.Title))
+	assert.Equal(t, strings.Repeat("è", 80)+"...", result.Title)
+}
+
+func TestSearchStopWords(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "ash-search")
+	defer os.RemoveAll(dir)
+
+	project := &Project{Path: dir, Analyzer: NewAnalyzer([]string{"en"}, nil)}
+	project.Index = newIndex(project)
+	_ = os.MkdirAll(filepath.Join(dir, ProjectLibraryFolder), 0755)
+	_ = ioutil.WriteFile(filepath.Join(dir, ProjectLibraryFolder, "spec.md"), []byte("the login page"), 0644)
+	_ = os.MkdirAll(filepath.Join(dir, ProjectBoardsFolder), 0755)
+	reIndexSource(project, ProjectLibraryFolder+"/spec.md")
+
+	results, err := Search(project, true, "the", "login")
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	results, _ = SearchDocuments(project, true, "the", "login")
+	assert.Len(t, results, 1)
+	results, _ = Search(project, true, "the")
+	assert.Len(t, results, 0)
+}
@@ -0,0 +1,221 @@
+package core
+
+var spanish = map[string]string{
+	"a":           "",
+	"al":          "",
+	"algo":        "",
+	"algunas":     "",
+	"algunos":     "",
+	"ante":        "",
+	"antes":       "",
+	"como":        "",
+	"con":         "",
+	"contra":      "",
+	"cual":        "",
+	"cuando":      "",
+	"de":          "",
+	"del":         "",
+	"desde":       "",
+	"donde":       "",
+	"durante":     "",
+	"e":           "",
+	"el":          "",
+	"ella":        "",
+	"ellas":       "",
+	"ellos":       "",
+	"en":          "",
+	"entre":       "",
+	"era":         "",
+	"erais":       "",
+	"eran":        "",
+	"eras":        "",
+	"eres":        "",
+	"es":          "",
+	"esa":         "",
+	"esas":        "",
+	"ese":         "",
+	"eso":         "",
+	"esos":        "",
+	"esta":        "",
+	"estaba":      "",
+	"estabais":    "",
+	"estaban":     "",
+	"estabas":     "",
+	"estamos":     "",
+	"estar":       "",
+	"estaremos":   "",
+	"estará":      "",
+	"estarán":     "",
+	"estarás":     "",
+	"estaré":      "",
+	"estaréis":    "",
+	"estas":       "",
+	"este":        "",
+	"estemos":     "",
+	"esto":        "",
+	"estos":       "",
+	"estoy":       "",
+	"estuve":      "",
+	"estuvieron":  "",
+	"estuvimos":   "",
+	"estuviste":   "",
+	"estuvisteis": "",
+	"estuvo":      "",
+	"está":        "",
+	"estábamos":   "",
+	"estáis":      "",
+	"están":       "",
+	"estás":       "",
+	"esté":        "",
+	"estéis":      "",
+	"estén":       "",
+	"estés":       "",
+	"fue":         "",
+	"fueron":      "",
+	"fui":         "",
+	"fuimos":      "",
+	"fuiste":      "",
+	"fuisteis":    "",
+	"ha":          "",
+	"habremos":    "",
+	"habrá":       "",
+	"habrán":      "",
+	"habrás":      "",
+	"habré":       "",
+	"habréis":     "",
+	"habéis":      "",
+	"había":       "",
+	"habíais":     "",
+	"habíamos":    "",
+	"habían":      "",
+	"habías":      "",
+	"han":         "",
+	"has":         "",
+	"hasta":       "",
+	"hay":         "",
+	"haya":        "",
+	"hayamos":     "",
+	"hayan":       "",
+	"hayas":       "",
+	"hayáis":      "",
+	"he":          "",
+	"hemos":       "",
+	"hube":        "",
+	"hubo":        "",
+	"la":          "",
+	"las":         "",
+	"le":          "",
+	"les":         "",
+	"lo":          "",
+	"los":         "",
+	"me":          "",
+	"mi":          "",
+	"mis":         "",
+	"mucho":       "",
+	"muchos":      "",
+	"muy":         "",
+	"más":         "",
+	"mí":          "",
+	"mía":         "",
+	"mías":        "",
+	"mío":         "",
+	"míos":        "",
+	"nada":        "",
+	"ni":          "",
+	"no":          "",
+	"nos":         "",
+	"nosotras":    "",
+	"nosotros":    "",
+	"nuestra":     "",
+	"nuestras":    "",
+	"nuestro":     "",
+	"nuestros":    "",
+	"o":           "",
+	"os":          "",
+	"otra":        "",
+	"otras":       "",
+	"otro":        "",
+	"otros":       "",
+	"para":        "",
+	"pero":        "",
+	"poco":        "",
+	"por":         "",
+	"porque":      "",
+	"que":         "",
+	"quien":       "",
+	"quienes":     "",
+	"qué":         "",
+	"se":          "",
+	"sea":         "",
+	"seamos":      "",
+	"sean":        "",
+	"seas":        "",
+	"seremos":     "",
+	"será":        "",
+	"serán":       "",
+	"serás":       "",
+	"seré":        "",
+	"seréis":      "",
+	"seáis":       "",
+	"sin":         "",
+	"sobre":       "",
+	"sois":        "",
+	"somos":       "",
+	"son":         "",
+	"soy":         "",
+	"su":          "",
+	"sus":         "",
+	"suya":        "",
+	"suyas":       "",
+	"suyo":        "",
+	"suyos":       "",
+	"sí":          "",
+	"también":     "",
+	"tanto":       "",
+	"te":          "",
+	"tenemos":     "",
+	"tenga":       "",
+	"tengamos":    "",
+	"tengan":      "",
+	"tengas":      "",
+	"tengo":       "",
+	"tengáis":     "",
+	"tenéis":      "",
+	"tenía":       "",
+	"teníais":     "",
+	"teníamos":    "",
+	"tenían":      "",
+	"tenías":      "",
+	"ti":          "",
+	"tiene":       "",
+	"tienen":      "",
+	"tienes":      "",
+	"todo":        "",
+	"todos":       "",
+	"tu":          "",
+	"tus":         "",
+	"tuve":        "",
+	"tuvieron":    "",
+	"tuvimos":     "",
+	"tuvo":        "",
+	"tuya":        "",
+	"tuyas":       "",
+	"tuyo":        "",
+	"tuyos":       "",
+	"tú":          "",
+	"un":          "",
+	"una":         "",
+	"uno":         "",
+	"unos":        "",
+	"vosotras":    "",
+	"vosotros":    "",
+	"vuestra":     "",
+	"vuestras":    "",
+	"vuestro":     "",
+	"vuestros":    "",
+	"y":           "",
+	"ya":          "",
+	"yo":          "",
+	"él":          "",
+	"éramos":      "",
+}
@@ -25,6 +25,9 @@ type UserInfo struct {
 	Icon        []byte            `json:"icon"`
 	Todo        []Todo            `json:"todo"`
 	Credentials map[string]string `json:"credentials"`
+	Filters     []Filter          `json:"filters"`
+	Absences    []Absence         `json:"absences"`
+	FeedToken   string            `json:"feedToken"`
 }
 
 // GetUserList returns the project users
@@ -43,6 +46,22 @@ func GetUserList(project *Project) []string {
 	return users
 }
 
+// IsAdmin returns true when the user can administer the project, e.g. break the locks of other users
+// or change the shared filters. When the project does not define admins, the user running the server
+// is the only admin.
+func IsAdmin(project *Project, user string) bool {
+	admins := project.Config.Public.Admins
+	if len(admins) == 0 {
+		return user == GetSystemUser()
+	}
+	for _, admin := range admins {
+		if admin == user {
+			return true
+		}
+	}
+	return false
+}
+
 // GetUserInfo returns information about the specified user
 func GetUserInfo(project *Project, user string) (userInfo UserInfo, err error) {
 	user = strings.ToLower(user)
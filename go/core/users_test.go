@@ -1 +1,17 @@
 package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsAdmin(t *testing.T) {
+	project := &Project{}
+	assert.True(t, IsAdmin(project, GetSystemUser()))
+	assert.False(t, IsAdmin(project, "mallory"))
+
+	project.Config.Public.Admins = []string{"alice"}
+	assert.True(t, IsAdmin(project, "alice"))
+	assert.False(t, IsAdmin(project, GetSystemUser()))
+}
@@ -0,0 +1,228 @@
+package core
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WatchDebounce is the quiet time after the last change before the index is updated
+const WatchDebounce = 500 * time.Millisecond
+
+// TaskChange is a task that has been created, updated or deleted on the file system
+type TaskChange struct {
+	Board   string
+	Name    string
+	Deleted bool
+}
+
+// TasksListener is notified after a batch of changes has been applied to the index
+type TasksListener func(project *Project, changes []TaskChange)
+
+var (
+	tasksListeners     []TasksListener
+	tasksListenersLock sync.Mutex
+)
+
+// AddTasksListener registers a function that is called every time tasks change in a project.
+// Packages with caches on tasks (e.g. query and gantt) use it for invalidation.
+func AddTasksListener(listener TasksListener) {
+	tasksListenersLock.Lock()
+	defer tasksListenersLock.Unlock()
+	tasksListeners = append(tasksListeners, listener)
+}
+
+func notifyTasksListeners(project *Project, changes []TaskChange) {
+	tasksListenersLock.Lock()
+	listeners := append([]TasksListener{}, tasksListeners...)
+	tasksListenersLock.Unlock()
+
+	for _, listener := range listeners {
+		listener(project, changes)
+	}
+}
+
+// Watcher collects changes in the boards, library and chat folders and applies them to the index after a debounce time
+type Watcher struct {
+	project *Project
+	fsWatch *fsnotify.Watcher
+	pending map[string]bool
+	timer   *time.Timer
+	lock    sync.Mutex
+	done    chan bool
+}
+
+func getWatcher(project *Project) *Watcher {
+	project.WatcherMutex.Lock()
+	defer project.WatcherMutex.Unlock()
+
+	if project.Watcher == nil {
+		project.Watcher = &Watcher{
+			project: project,
+			pending: make(map[string]bool),
+		}
+	}
+	return project.Watcher
+}
+
+func addWatchFolders(fsWatch *fsnotify.Watcher, folder string) {
+	_ = filepath.Walk(folder, func(p string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if p != folder && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if err := fsWatch.Add(p); err != nil {
+			logrus.Warnf("cannot watch folder %s: %v", p, err)
+		}
+		return nil
+	})
+}
+
+// StartWatcher monitors boards, library and chat of the project and keeps the index updated in background.
+func StartWatcher(project *Project) error {
+	w := getWatcher(project)
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.fsWatch != nil {
+		return nil
+	}
+
+	fsWatch, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := fsWatch.Add(filepath.Join(project.Path, ProjectBoardsFolder)); err != nil {
+		_ = fsWatch.Close()
+		return err
+	}
+	for _, folder := range []string{ProjectBoardsFolder, ProjectLibraryFolder, ProjectChatFolder} {
+		addWatchFolders(fsWatch, filepath.Join(project.Path, folder))
+	}
+
+	w.fsWatch = fsWatch
+	w.done = make(chan bool)
+	go w.run(fsWatch, w.done)
+
+	logrus.Infof("Watching boards, library and chat in project %s", project.Path)
+	return nil
+}
+
+// StopWatcher stops the monitoring of the project boards.
+func StopWatcher(project *Project) {
+	w := getWatcher(project)
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.fsWatch == nil {
+		return
+	}
+	close(w.done)
+	_ = w.fsWatch.Close()
+	w.fsWatch = nil
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+// QueueReIndex schedules the index update for a task. The update is applied in background after
+// WatchDebounce, so that many changes close in time are processed together.
+func QueueReIndex(project *Project, board string, name string) {
+	getWatcher(project).queue(filepath.Join(ProjectBoardsFolder, board, name+TaskFileExt))
+}
+
+// QueueReIndexSource schedules the index update for a library document or a chat message.
+// The path is relative to the project.
+func QueueReIndexSource(project *Project, path string) {
+	getWatcher(project).queue(filepath.Clean(path))
+}
+
+func (w *Watcher) run(fsWatch *fsnotify.Watcher, done chan bool) {
+	folder := w.project.Path
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-fsWatch.Events:
+			if !ok {
+				return
+			}
+			rel, err := filepath.Rel(folder, event.Name)
+			if err != nil {
+				continue
+			}
+
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				if event.Op&fsnotify.Create == fsnotify.Create {
+					addWatchFolders(fsWatch, event.Name)
+					logrus.Debugf("Watching new folder %s", rel)
+				}
+				continue
+			}
+			if isTaskPath(rel) || getSourceType(filepath.ToSlash(rel)) != "" {
+				w.queue(rel)
+			}
+		case err, ok := <-fsWatch.Errors:
+			if !ok {
+				return
+			}
+			logrus.Warnf("error while watching %s: %v", folder, err)
+		}
+	}
+}
+
+// isTaskPath returns true when the path relative to the project is a task in a board
+func isTaskPath(rel string) bool {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	return len(parts) == 3 && parts[0] == ProjectBoardsFolder && filepath.Ext(rel) == TaskFileExt
+}
+
+func (w *Watcher) queue(rel string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.pending[rel] = true
+	if w.timer == nil {
+		w.timer = time.AfterFunc(WatchDebounce, w.flush)
+	} else {
+		w.timer.Reset(WatchDebounce)
+	}
+}
+
+func (w *Watcher) flush() {
+	w.lock.Lock()
+	pending := w.pending
+	w.pending = make(map[string]bool)
+	w.lock.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	changes := make([]TaskChange, 0, len(pending))
+	sources := make([]string, 0)
+	for rel := range pending {
+		if !isTaskPath(rel) {
+			sources = append(sources, rel)
+			continue
+		}
+		board, file := filepath.Split(rel)
+		_, err := os.Stat(filepath.Join(w.project.Path, rel))
+		changes = append(changes, TaskChange{
+			Board:   filepath.Base(board),
+			Name:    strings.TrimSuffix(file, TaskFileExt),
+			Deleted: os.IsNotExist(err),
+		})
+	}
+
+	if err := ReIndexTasks(w.project, changes, sources...); err != nil {
+		logrus.Warnf("cannot update index for %s: %v", w.project.Path, err)
+	}
+	notifyTasksListeners(w.project, changes)
+}
@@ -21,3 +21,12 @@ func GetFileOwner(path string) (string, error) {
 	}
 	return user.Name, nil
 }
+
+// GetLinks returns the number of hard links to a file
+func GetLinks(path string) (uint64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(info.Sys().(*syscall.Stat_t).Nlink), nil
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/hectane/go-acl/api"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/windows"
+	"os"
 	"os/user"
 )
 
@@ -37,3 +38,18 @@ func GetFileOwner(path string) (string, error) {
 	logrus.Debugf("Owner of %s is %s (%s)", path, u.Name, owner)
 	return u.Name, nil
 }
+
+// GetLinks returns the number of hard links to a file
+func GetLinks(path string) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var info windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(windows.Handle(file.Fd()), &info); err != nil {
+		return 0, err
+	}
+	return uint64(info.NumberOfLinks), nil
+}
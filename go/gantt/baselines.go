@@ -0,0 +1,254 @@
+package gantt
+
+import (
+	"almost-scrum/core"
+	"almost-scrum/fs"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProgressProperty is the completion of a task in percent
+const ProgressProperty = "Progress"
+
+// ErrInvalidBaseline is returned when the name of a baseline is not valid
+var ErrInvalidBaseline = errors.New("invalid baseline name")
+
+// BaselineTask is the snapshot of a task when the baseline was saved
+type BaselineTask struct {
+	Board    string `json:"board" yaml:"board"`
+	Name     string `json:"name" yaml:"name"`
+	Start    string `json:"start" yaml:"start"`
+	End      string `json:"end" yaml:"end"`
+	Progress int    `json:"progress" yaml:"progress"`
+}
+
+// Baseline is a named snapshot of the plan. Baselines are stored in the project so that they are
+// shared with git and the federation.
+type Baseline struct {
+	Name    string         `json:"name" yaml:"name"`
+	Created time.Time      `json:"created" yaml:"created"`
+	Author  string         `json:"author" yaml:"author"`
+	Tasks   []BaselineTask `json:"tasks" yaml:"tasks"`
+}
+
+// BaselineInfo describes a baseline without its tasks
+type BaselineInfo struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Author  string    `json:"author"`
+}
+
+// Slippage compares a task in the baseline with its current state. Slippages are in days; a positive
+// value is a delay. Status is added when the task is not in the baseline and removed when it is
+// not in the gantt anymore.
+type Slippage struct {
+	Board            string    `json:"board"`
+	Name             string    `json:"name"`
+	Status           string    `json:"status"`
+	BaselineStart    time.Time `json:"baselineStart"`
+	BaselineEnd      time.Time `json:"baselineEnd"`
+	BaselineProgress int       `json:"baselineProgress"`
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	Progress         int       `json:"progress"`
+	StartSlippage    float64   `json:"startSlippage"`
+	EndSlippage      float64   `json:"endSlippage"`
+}
+
+// Comparison is the difference between a baseline and the current plan. FinishSlippage is the
+// delay of the last task in the plan.
+type Comparison struct {
+	Baseline       BaselineInfo `json:"baseline"`
+	Tasks          []Slippage   `json:"tasks"`
+	BaselineFinish time.Time    `json:"baselineFinish"`
+	Finish         time.Time    `json:"finish"`
+	FinishSlippage float64      `json:"finishSlippage"`
+	Delayed        int          `json:"delayed"`
+}
+
+func getBaselinePath(project *core.Project, name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\:`) || strings.HasPrefix(name, ".") {
+		return "", ErrInvalidBaseline
+	}
+	return filepath.Join(project.Path, core.ProjectBaselinesFolder, name+".yaml"), nil
+}
+
+func getProgress(task core.Task) int {
+	progress, _ := strconv.Atoi(strings.TrimSpace(task.Properties[ProgressProperty]))
+	return progress
+}
+
+func daysBetween(from, to time.Time) float64 {
+	return to.Sub(from).Hours() / 24
+}
+
+// SaveBaseline freezes the dates and the progress of all tasks in the gantt with the given name
+func SaveBaseline(project *core.Project, name string, author string) (Baseline, error) {
+	path, err := getBaselinePath(project, name)
+	if err != nil {
+		return Baseline{}, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return Baseline{}, core.ErrExists
+	}
+
+	tasks, err := GetTasks(project)
+	if err != nil {
+		return Baseline{}, err
+	}
+	baseline := Baseline{
+		Name:    name,
+		Created: time.Now(),
+		Author:  author,
+		Tasks:   make([]BaselineTask, 0, len(tasks)),
+	}
+	for _, t := range tasks {
+		baseline.Tasks = append(baseline.Tasks, BaselineTask{
+			Board:    t.Board,
+			Name:     t.Name,
+			Start:    t.Task.Properties["Start"],
+			End:      t.Task.Properties["End"],
+			Progress: getProgress(t.Task),
+		})
+	}
+
+	_ = os.MkdirAll(filepath.Dir(path), 0755)
+	return baseline, fs.WriteYaml(path, &baseline)
+}
+
+// GetBaseline reads the baseline with the given name
+func GetBaseline(project *core.Project, name string) (Baseline, error) {
+	var baseline Baseline
+	path, err := getBaselinePath(project, name)
+	if err != nil {
+		return baseline, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return baseline, core.ErrNoFound
+	}
+	err = fs.ReadYaml(path, &baseline)
+	return baseline, err
+}
+
+// ListBaselines returns the baselines in the project, the most recent first
+func ListBaselines(project *core.Project) ([]BaselineInfo, error) {
+	infos := make([]BaselineInfo, 0)
+	files, err := ioutil.ReadDir(filepath.Join(project.Path, core.ProjectBaselinesFolder))
+	if os.IsNotExist(err) {
+		return infos, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".yaml" {
+			continue
+		}
+		baseline, err := GetBaseline(project, strings.TrimSuffix(file.Name(), ".yaml"))
+		if core.IsErr(err, "cannot read baseline %s", file.Name()) {
+			continue
+		}
+		infos = append(infos, BaselineInfo{Name: baseline.Name, Created: baseline.Created, Author: baseline.Author})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Created.After(infos[j].Created)
+	})
+	return infos, nil
+}
+
+// DeleteBaseline removes the baseline with the given name
+func DeleteBaseline(project *core.Project, name string) error {
+	path, err := getBaselinePath(project, name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return core.ErrNoFound
+	}
+	return err
+}
+
+// Compare computes the slippage of each task and of the whole plan since the baseline
+func Compare(baseline Baseline, tasks []*Task) Comparison {
+	comparison := Comparison{
+		Baseline: BaselineInfo{Name: baseline.Name, Created: baseline.Created, Author: baseline.Author},
+		Tasks:    make([]Slippage, 0, len(tasks)),
+	}
+
+	planned := make(map[string]bool)
+	for _, b := range baseline.Tasks {
+		planned[b.Board+"/"+b.Name] = true
+		slippage := Slippage{Board: b.Board, Name: b.Name, BaselineProgress: b.Progress}
+		slippage.BaselineStart, _ = parseDate(b.Start)
+		slippage.BaselineEnd, _ = parseDate(b.End)
+		if slippage.BaselineEnd.After(comparison.BaselineFinish) {
+			comparison.BaselineFinish = slippage.BaselineEnd
+		}
+
+		var current *Task
+		for _, t := range tasks {
+			if t.Board == b.Board && t.Name == b.Name {
+				current = t
+				break
+			}
+		}
+		if current == nil {
+			slippage.Status = "removed"
+			comparison.Tasks = append(comparison.Tasks, slippage)
+			continue
+		}
+
+		slippage.Start, _ = parseDate(current.Task.Properties["Start"])
+		slippage.End, _ = parseDate(current.Task.Properties["End"])
+		slippage.Progress = getProgress(current.Task)
+		if !slippage.Start.IsZero() && !slippage.BaselineStart.IsZero() {
+			slippage.StartSlippage = daysBetween(slippage.BaselineStart, slippage.Start)
+		}
+		if !slippage.End.IsZero() && !slippage.BaselineEnd.IsZero() {
+			slippage.EndSlippage = daysBetween(slippage.BaselineEnd, slippage.End)
+		}
+		if slippage.EndSlippage > 0 {
+			comparison.Delayed++
+		}
+		comparison.Tasks = append(comparison.Tasks, slippage)
+	}
+
+	for _, t := range tasks {
+		end, _ := parseDate(t.Task.Properties["End"])
+		if end.After(comparison.Finish) {
+			comparison.Finish = end
+		}
+		if planned[t.Board+"/"+t.Name] {
+			continue
+		}
+		slippage := Slippage{Board: t.Board, Name: t.Name, Status: "added", End: end, Progress: getProgress(t.Task)}
+		slippage.Start, _ = parseDate(t.Task.Properties["Start"])
+		comparison.Tasks = append(comparison.Tasks, slippage)
+	}
+
+	if !comparison.Finish.IsZero() && !comparison.BaselineFinish.IsZero() {
+		comparison.FinishSlippage = daysBetween(comparison.BaselineFinish, comparison.Finish)
+	}
+	return comparison
+}
+
+// GetComparison compares the baseline with the given name with the current tasks in the gantt
+func GetComparison(project *core.Project, name string) (Comparison, error) {
+	baseline, err := GetBaseline(project, name)
+	if err != nil {
+		return Comparison{}, err
+	}
+	tasks, err := GetTasks(project)
+	if err != nil {
+		return Comparison{}, err
+	}
+	return Compare(baseline, tasks), nil
+}
@@ -0,0 +1,34 @@
+package gantt
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCompareBaseline(t *testing.T) {
+	baseline := Baseline{
+		Name: "v1",
+		Tasks: []BaselineTask{
+			{Board: "backlog", Name: "1.Design", Start: "2021-03-01", End: "2021-03-05", Progress: 50},
+			{Board: "backlog", Name: "2.Build", Start: "2021-03-05", End: "2021-03-15"},
+			{Board: "backlog", Name: "3.Docs", Start: "2021-03-03", End: "2021-03-06"},
+		},
+	}
+	tasks := []*Task{
+		newTask("1.Design", "2021-03-01", "2021-03-08", ""),
+		newTask("2.Build", "2021-03-08", "2021-03-20", "1"),
+		newTask("4.Release", "2021-03-20", "2021-03-22", "2"),
+	}
+	tasks[0].Task.Properties[ProgressProperty] = "80"
+
+	comparison := Compare(baseline, tasks)
+	assert.Len(t, comparison.Tasks, 4)
+	assert.Equal(t, 3.0, comparison.Tasks[0].EndSlippage)
+	assert.Equal(t, 80, comparison.Tasks[0].Progress)
+	assert.Equal(t, 50, comparison.Tasks[0].BaselineProgress)
+	assert.Equal(t, 3.0, comparison.Tasks[1].StartSlippage)
+	assert.Equal(t, "removed", comparison.Tasks[2].Status)
+	assert.Equal(t, "added", comparison.Tasks[3].Status)
+	assert.Equal(t, 2, comparison.Delayed)
+	assert.Equal(t, 7.0, comparison.FinishSlippage)
+}
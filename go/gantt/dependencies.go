@@ -0,0 +1,257 @@
+package gantt
+
+import (
+	"almost-scrum/core"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DepsProperty is the property with the dependencies of a task, e.g. "3.Design, 5 SS+2d".
+// Each dependency is a task name or id, optionally followed by the type (FS or SS) and a lag in days.
+const DepsProperty = "Deps"
+
+type DependencyType string
+
+const (
+	FinishToStart DependencyType = "FS"
+	StartToStart  DependencyType = "SS"
+)
+
+const day = 24 * time.Hour
+
+var dependencyMatch = regexp.MustCompile(`^(.*?)\s+(FS|SS)?([+-]\d+)?d?$`)
+
+var dateLayouts = []string{time.RFC3339, "2006-01-02", "2006-01-02 15:04"}
+
+// Dependency is a constraint on the start of a task: it cannot start before the end (FS) or the
+// start (SS) of another task plus the lag
+type Dependency struct {
+	Task string         `json:"task"`
+	Type DependencyType `json:"type"`
+	Lag  int            `json:"lag"`
+}
+
+// Plan is the result of the critical path analysis on the tasks in the gantt
+type Plan struct {
+	Tasks        []*Task   `json:"tasks"`
+	CriticalPath []string  `json:"criticalPath"`
+	Start        time.Time `json:"start"`
+	Finish       time.Time `json:"finish"`
+}
+
+func parseDate(s string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseEnd reads the End property of a task. A date without time is the last day of the task,
+// so the end used in the calculations is the following midnight.
+func parseEnd(s string) (time.Time, bool) {
+	end, ok := parseDate(s)
+	if ok && len(strings.TrimSpace(s)) == len(DateLayout) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, ok
+}
+
+// parseDependencies reads the dependencies in the Deps property. Refs are the names or the ids of the tasks.
+func parseDependencies(deps string) []Dependency {
+	var dependencies []Dependency
+	for _, dep := range strings.Split(deps, ",") {
+		dep = strings.TrimSpace(dep)
+		if dep == "" {
+			continue
+		}
+
+		dependency := Dependency{Task: dep, Type: FinishToStart}
+		if m := dependencyMatch.FindStringSubmatch(dep); m != nil && m[1] != "" && (m[2] != "" || m[3] != "") {
+			dependency.Task = m[1]
+			if m[2] != "" {
+				dependency.Type = DependencyType(m[2])
+			}
+			dependency.Lag, _ = strconv.Atoi(m[3])
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies
+}
+
+func findTask(tasks []*Task, ref string) *Task {
+	for _, t := range tasks {
+		if t.Name == ref {
+			return t
+		}
+	}
+	if id, err := strconv.Atoi(ref); err == nil {
+		for _, t := range tasks {
+			if taskId, _ := core.ExtractTaskId(t.Name); int(taskId) == id {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// constraint returns the earliest start of task because of the dependency on other
+func constraint(dependency Dependency, start, end time.Time) time.Time {
+	lag := time.Duration(dependency.Lag) * day
+	if dependency.Type == StartToStart {
+		return start.Add(lag)
+	}
+	return end.Add(lag)
+}
+
+// sortTopologically returns the tasks in order of dependency. Tasks in a loop are not returned.
+func sortTopologically(tasks []*Task, preds map[*Task][]*Task) []*Task {
+	inDegree := make(map[*Task]int)
+	succs := make(map[*Task][]*Task)
+	for _, t := range tasks {
+		inDegree[t] += 0
+		for _, p := range preds[t] {
+			inDegree[t]++
+			succs[p] = append(succs[p], t)
+		}
+	}
+
+	var queue, sorted []*Task
+	for _, t := range tasks {
+		if inDegree[t] == 0 {
+			queue = append(queue, t)
+		}
+	}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		sorted = append(sorted, t)
+		for _, s := range succs[t] {
+			inDegree[s]--
+			if inDegree[s] == 0 {
+				queue = append(queue, s)
+			}
+		}
+	}
+	return sorted
+}
+
+// Schedule computes earliest and latest start, slack and critical path of the tasks with the
+// critical path method. Tasks without valid dates and tasks in a dependency loop are flagged and
+// excluded from the analysis. Tasks whose dates violate a dependency are flagged too.
+func Schedule(tasks []*Task) Plan {
+	plan := Plan{Tasks: tasks, CriticalPath: []string{}}
+
+	var scheduled []*Task
+	for _, t := range tasks {
+		t.Dependencies = parseDependencies(t.Task.Properties[DepsProperty])
+		t.Violations = nil
+		t.Critical = false
+		start, ok1 := parseDate(t.Task.Properties["Start"])
+		end, ok2 := parseEnd(t.Task.Properties["End"])
+		if !ok1 || !ok2 || end.Before(start) {
+			t.Violations = append(t.Violations, "missing or invalid Start and End")
+			continue
+		}
+		t.Start, t.End = start, end
+		scheduled = append(scheduled, t)
+	}
+
+	preds := make(map[*Task][]*Task)
+	deps := make(map[*Task][]Dependency)
+	for _, t := range scheduled {
+		for _, dependency := range t.Dependencies {
+			p := findTask(scheduled, dependency.Task)
+			if p == nil {
+				t.Violations = append(t.Violations, fmt.Sprintf("unknown or unscheduled task %s", dependency.Task))
+				continue
+			}
+			preds[t] = append(preds[t], p)
+			deps[t] = append(deps[t], dependency)
+			if t.Start.Before(constraint(dependency, p.Start, p.End)) {
+				t.Violations = append(t.Violations, fmt.Sprintf("starts before %s %s%+dd allows",
+					p.Name, dependency.Type, dependency.Lag))
+			}
+		}
+	}
+
+	sorted := sortTopologically(scheduled, preds)
+	if len(sorted) < len(scheduled) {
+		for _, t := range scheduled {
+			if !containsTask(sorted, t) {
+				t.Violations = append(t.Violations, "in or after a dependency loop")
+			}
+		}
+	}
+	if len(sorted) == 0 {
+		return plan
+	}
+
+	// Forward pass: tasks without dependencies keep their start
+	plan.Start = sorted[0].Start
+	for _, t := range sorted {
+		duration := t.End.Sub(t.Start)
+		t.EarliestStart = t.Start
+		for i, p := range preds[t] {
+			c := constraint(deps[t][i], p.EarliestStart, p.EarliestStart.Add(p.End.Sub(p.Start)))
+			if i == 0 || c.After(t.EarliestStart) {
+				t.EarliestStart = c
+			}
+		}
+		t.EarliestFinish = t.EarliestStart.Add(duration)
+		if t.EarliestStart.Before(plan.Start) {
+			plan.Start = t.EarliestStart
+		}
+		if t.EarliestFinish.After(plan.Finish) {
+			plan.Finish = t.EarliestFinish
+		}
+	}
+
+	// Backward pass from the end of the project
+	for _, t := range sorted {
+		t.LatestFinish = plan.Finish
+	}
+	for i := len(sorted) - 1; i >= 0; i-- {
+		t := sorted[i]
+		duration := t.End.Sub(t.Start)
+		t.LatestStart = t.LatestFinish.Add(-duration)
+		for j, p := range preds[t] {
+			dependency := deps[t][j]
+			lag := time.Duration(dependency.Lag) * day
+			latest := t.LatestStart.Add(-lag)
+			if dependency.Type == StartToStart {
+				latest = latest.Add(p.End.Sub(p.Start))
+			}
+			if latest.Before(p.LatestFinish) {
+				p.LatestFinish = latest
+			}
+		}
+	}
+
+	for _, t := range sorted {
+		t.Slack = t.LatestStart.Sub(t.EarliestStart).Hours() / 24
+		if t.Slack <= 0 {
+			t.Critical = true
+			plan.CriticalPath = append(plan.CriticalPath, t.Name)
+		}
+	}
+	sort.SliceStable(plan.CriticalPath, func(i, j int) bool {
+		ti, tj := findTask(sorted, plan.CriticalPath[i]), findTask(sorted, plan.CriticalPath[j])
+		return ti.EarliestStart.Before(tj.EarliestStart)
+	})
+	return plan
+}
+
+func containsTask(tasks []*Task, task *Task) bool {
+	for _, t := range tasks {
+		if t == task {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package gantt
+
+import (
+	"almost-scrum/core"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTask(name string, start string, end string, deps string) *Task {
+	return &Task{
+		Board: "backlog",
+		Name:  name,
+		Task: core.Task{Properties: map[string]string{
+			"Start": start, "End": end, DepsProperty: deps,
+		}},
+	}
+}
+
+func TestParseDependencies(t *testing.T) {
+	assert.Equal(t, []Dependency{
+		{Task: "1.Design", Type: FinishToStart},
+		{Task: "2", Type: StartToStart, Lag: 2},
+		{Task: "3.Login page", Type: FinishToStart, Lag: -1},
+	}, parseDependencies("1.Design, 2 SS+2d,3.Login page -1d"))
+}
+
+func TestSchedule(t *testing.T) {
+	tasks := []*Task{
+		newTask("1.Design", "2021-03-01", "2021-03-04", ""),
+		newTask("2.Build", "2021-03-05", "2021-03-14", "1"),
+		newTask("3.Docs", "2021-03-03", "2021-03-05", "1.Design SS+2d"),
+		newTask("4.Release", "2021-03-14", "2021-03-15", "2.Build, 3.Docs"),
+		newTask("5.Loop", "2021-03-01", "2021-03-01", "6"),
+		newTask("6.Loop", "2021-03-01", "2021-03-01", "5"),
+	}
+	plan := Schedule(tasks)
+
+	assert.Equal(t, []string{"1.Design", "2.Build", "4.Release"}, plan.CriticalPath)
+	assert.Equal(t, 9.0, tasks[2].Slack)
+	assert.Equal(t, "2021-03-15", tasks[3].EarliestStart.Format("2006-01-02"))
+	assert.Len(t, tasks[3].Violations, 1)
+	assert.Empty(t, tasks[1].Violations)
+	assert.Contains(t, tasks[4].Violations, "in or after a dependency loop")
+}
@@ -3,20 +3,44 @@ package gantt
 import (
 	"almost-scrum/core"
 	"github.com/patrickmn/go-cache"
+	"sync"
 	"time"
 )
 
 var (
 	ganttCache = cache.New(5*time.Minute, 10*time.Minute)
+	stateMutex sync.Mutex
 )
 
+func init() {
+	core.AddTasksListener(func(project *core.Project, changes []core.TaskChange) {
+		ganttCache.Delete(project.Config.UUID)
+	})
+}
+
+// Task is a task in the gantt. Dates, slack and violations are computed by Schedule; slack is in days.
+// WorkingDays is the duration of the task in the calendar of its owner.
 type Task struct {
-	Board string    `json:"board"`
-	Name  string    `json:"name"`
-	Task  core.Task `json:"task"`
+	Board          string       `json:"board"`
+	Name           string       `json:"name"`
+	Task           core.Task    `json:"task"`
+	Start          time.Time    `json:"start"`
+	End            time.Time    `json:"end"`
+	Dependencies   []Dependency `json:"dependencies"`
+	EarliestStart  time.Time    `json:"earliestStart"`
+	EarliestFinish time.Time    `json:"earliestFinish"`
+	LatestStart    time.Time    `json:"latestStart"`
+	LatestFinish   time.Time    `json:"latestFinish"`
+	Slack          float64      `json:"slack"`
+	WorkingDays    int          `json:"workingDays"`
+	Critical       bool         `json:"critical"`
+	Violations     []string     `json:"violations"`
 }
 
+// State is the cache of the gantt tasks of a project. Tracked is the modification time of the tasks
+// when they were read. The mutex guards both, since requests share the state.
 type State struct {
+	sync.Mutex
 	Tasks   []*Task
 	Tracked map[string]time.Time
 }
@@ -56,21 +80,50 @@ func DeriveTask(project *core.Project, info core.TaskInfo) (*Task, error) {
 	}, nil
 }
 
-func GetTasks(project *core.Project) ([]*Task, error) {
-	var state *State
-	s, found := ganttCache.Get(project.Config.UUID)
-	if found {
-		state = s.(*State)
-	} else {
-		state = &State{}
-		ganttCache.Set(project.Config.UUID, state, cache.DefaultExpiration)
+// getState returns the cached gantt state of a project
+func getState(project *core.Project) *State {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	if s, found := ganttCache.Get(project.Config.UUID); found {
+		return s.(*State)
 	}
+	state := &State{Tracked: make(map[string]time.Time)}
+	ganttCache.Set(project.Config.UUID, state, cache.DefaultExpiration)
+	return state
+}
 
+// setTask replaces the task with the same name in the state; a nil task removes it
+func (s *State) setTask(name string, task *Task) {
+	for idx, t := range s.Tasks {
+		if t.Name == name {
+			if task == nil {
+				s.Tasks = append(s.Tasks[:idx], s.Tasks[idx+1:]...)
+			} else {
+				s.Tasks[idx] = task
+			}
+			return
+		}
+	}
+	if task != nil {
+		s.Tasks = append(s.Tasks, task)
+	}
+}
+
+// GetTasks returns the tasks in the gantt. The tasks are copies, so callers can change them.
+func GetTasks(project *core.Project) ([]*Task, error) {
 	infos, err := core.ListTasks(project, "", "")
 	if err != nil {
 		return nil, err
 	}
+
+	state := getState(project)
+	state.Lock()
+	defer state.Unlock()
+
+	listed := make(map[string]bool, len(infos))
 	for _, info := range infos {
+		listed[info.Name] = true
 		modTime, found := state.Tracked[info.Name]
 		if found && modTime == info.ModTime {
 			continue
@@ -80,17 +133,39 @@ func GetTasks(project *core.Project) ([]*Task, error) {
 		if err != nil {
 			return nil, err
 		}
-		if task != nil {
-			for idx, t := range state.Tasks {
-				if t.Name == task.Name {
-					state.Tasks[idx] = task
-					goto done
-				}
-			}
-			state.Tasks = append(state.Tasks, task)
+		state.Tracked[info.Name] = info.ModTime
+		state.setTask(info.Name, task)
+	}
+
+	// tasks no longer listed have been deleted
+	for name := range state.Tracked {
+		if !listed[name] {
+			delete(state.Tracked, name)
+			state.setTask(name, nil)
 		}
-		done:
 	}
 
-	return state.Tasks, nil
+	tasks := make([]*Task, 0, len(state.Tasks))
+	for _, t := range state.Tasks {
+		c := *t
+		tasks = append(tasks, &c)
+	}
+	return tasks, nil
+}
+
+// GetPlan returns the tasks in the gantt with the analysis of their dependencies
+func GetPlan(project *core.Project) (Plan, error) {
+	tasks, err := GetTasks(project)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan := Schedule(tasks)
+
+	calendar := core.GetCalendar(project)
+	for _, t := range plan.Tasks {
+		if !t.End.IsZero() {
+			t.WorkingDays = calendar.WorkingDays(t.Task.Properties["Owner"], truncateDay(t.Start), t.End)
+		}
+	}
+	return plan, nil
 }
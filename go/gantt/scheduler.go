@@ -0,0 +1,268 @@
+package gantt
+
+import (
+	"almost-scrum/core"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DurationProperty is the explicit duration of a task in working days, e.g. 3 or 3d
+	DurationProperty = "Duration"
+	// PointsProperty is the estimate of a task, converted to days with DaysPerPoint in the project configuration
+	PointsProperty = "Points"
+	// PinnedProperty marks tasks whose dates are set by hand and are not moved by the scheduler
+	PinnedProperty = "Pinned"
+	// DateLayout is the format of dates written by the scheduler
+	DateLayout = "2006-01-02"
+)
+
+// maxScheduleDays limits the search for free days when an owner is not available
+const maxScheduleDays = 5 * 365
+
+// Calendar tells on which days a user can work
+type Calendar interface {
+	IsWorkingDay(user string, day time.Time) bool
+}
+
+// Weekdays is the default calendar when the project has none: everybody works from Monday to Friday
+type Weekdays struct{}
+
+func (Weekdays) IsWorkingDay(_ string, day time.Time) bool {
+	return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+}
+
+// Change is a task whose dates are computed by the scheduler. End is the last day of the task, as in
+// the End property. Tasks that cannot be scheduled have zero dates and the reason in Violations.
+type Change struct {
+	Board      string    `json:"board"`
+	Name       string    `json:"name"`
+	Owner      string    `json:"owner"`
+	OldStart   string    `json:"oldStart"`
+	OldEnd     string    `json:"oldEnd"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Pinned     bool      `json:"pinned"`
+	Days       int       `json:"days"`
+	Moved      bool      `json:"moved"`
+	Violations []string  `json:"violations"`
+}
+
+// ScheduleOptions configures the scheduler. Tasks are not scheduled before From.
+type ScheduleOptions struct {
+	From         time.Time
+	Calendar     Calendar
+	DaysPerPoint float64
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func isPinned(task *Task) bool {
+	pinned, _ := strconv.ParseBool(strings.TrimSpace(task.Task.Properties[PinnedProperty]))
+	return pinned
+}
+
+// getDays returns the working days required by the task from Duration or from Points
+func getDays(task *Task, daysPerPoint float64) (int, bool) {
+	if d := strings.TrimSuffix(strings.TrimSpace(task.Task.Properties[DurationProperty]), "d"); d != "" {
+		if days, err := strconv.ParseFloat(d, 64); err == nil && days > 0 {
+			return int(math.Ceil(days)), true
+		}
+	}
+	if p := strings.TrimSpace(task.Task.Properties[PointsProperty]); p != "" {
+		if points, err := strconv.ParseFloat(p, 64); err == nil && points > 0 {
+			return int(math.Max(1, math.Ceil(points*daysPerPoint))), true
+		}
+	}
+	return 0, false
+}
+
+// allocate finds the first days from the earliest day in which the owner works and is not busy
+// with other tasks. It returns the first and the last day of the task, and false when the owner
+// has not enough free days within maxScheduleDays.
+func allocate(owner string, earliest time.Time, days int, calendar Calendar,
+	busy map[string]map[time.Time]bool) (time.Time, time.Time, bool) {
+	var free []time.Time
+	day := earliest
+	for i := 0; len(free) < days && i < maxScheduleDays; i++ {
+		if calendar.IsWorkingDay(owner, day) && !busy[owner][day] {
+			free = append(free, day)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	if len(free) < days {
+		return time.Time{}, time.Time{}, false
+	}
+	for _, day := range free {
+		busy[owner][day] = true
+	}
+	return free[0], free[len(free)-1], true
+}
+
+// AutoSchedule computes the dates of the tasks from their estimates, their dependencies and the
+// availability of their owners. An owner works on one task at a time. Pinned tasks and tasks without
+// estimate keep their dates. Tasks in a dependency loop, tasks without estimate and dates, tasks whose
+// owner has no free days and the tasks depending on them are returned with a violation and are not moved.
+func AutoSchedule(tasks []*Task, options ScheduleOptions) []Change {
+	if options.Calendar == nil {
+		options.Calendar = Weekdays{}
+	}
+	if options.DaysPerPoint <= 0 {
+		options.DaysPerPoint = 1
+	}
+	from := truncateDay(options.From)
+
+	preds := make(map[*Task][]*Task)
+	deps := make(map[*Task][]Dependency)
+	for _, t := range tasks {
+		t.Start, t.End = time.Time{}, time.Time{}
+		t.Dependencies = parseDependencies(t.Task.Properties[DepsProperty])
+		for _, dependency := range t.Dependencies {
+			if p := findTask(tasks, dependency.Task); p != nil {
+				preds[t] = append(preds[t], p)
+				deps[t] = append(deps[t], dependency)
+			}
+		}
+	}
+	sorted := sortTopologically(tasks, preds)
+
+	busy := make(map[string]map[time.Time]bool)
+	reserve := func(owner string, start, end time.Time) {
+		if busy[owner] == nil {
+			busy[owner] = make(map[time.Time]bool)
+		}
+		for day := truncateDay(start); day.Before(end); day = day.AddDate(0, 0, 1) {
+			busy[owner][day] = true
+		}
+	}
+
+	changes := make([]Change, 0, len(tasks))
+	fixed := make(map[*Task]bool)
+	failed := make(map[*Task]bool)
+	for _, t := range sorted {
+		start, ok1 := parseDate(t.Task.Properties["Start"])
+		end, ok2 := parseEnd(t.Task.Properties["End"])
+		if _, estimated := getDays(t, options.DaysPerPoint); isPinned(t) || !estimated {
+			if ok1 && ok2 {
+				t.Start, t.End = start, end
+				reserve(t.Task.Properties["Owner"], start, end)
+				fixed[t] = true
+			}
+		}
+	}
+
+	for _, t := range tasks {
+		if !containsTask(sorted, t) {
+			changes = append(changes, Change{
+				Board:      t.Board,
+				Name:       t.Name,
+				Owner:      t.Task.Properties["Owner"],
+				OldStart:   t.Task.Properties["Start"],
+				OldEnd:     t.Task.Properties["End"],
+				Pinned:     isPinned(t),
+				Violations: []string{"in or after a dependency loop"},
+			})
+		}
+	}
+
+	for _, t := range sorted {
+		owner := t.Task.Properties["Owner"]
+		change := Change{
+			Board:    t.Board,
+			Name:     t.Name,
+			Owner:    owner,
+			OldStart: t.Task.Properties["Start"],
+			OldEnd:   t.Task.Properties["End"],
+			Pinned:   isPinned(t),
+		}
+		if fixed[t] {
+			change.Start, _ = parseDate(change.OldStart)
+			change.End, _ = parseDate(change.OldEnd)
+			changes = append(changes, change)
+			continue
+		}
+		days, estimated := getDays(t, options.DaysPerPoint)
+		if !estimated {
+			change.Violations = []string{"no estimate and no dates"}
+			failed[t] = true
+			changes = append(changes, change)
+			continue
+		}
+
+		earliest := from
+		for i, p := range preds[t] {
+			if failed[p] {
+				change.Violations = append(change.Violations, fmt.Sprintf("depends on unscheduled task %s", p.Name))
+			}
+			if p.End.IsZero() {
+				continue
+			}
+			if c := truncateDay(constraint(deps[t][i], p.Start, p.End)); c.After(earliest) {
+				earliest = c
+			}
+		}
+		change.Days = days
+		if len(change.Violations) > 0 {
+			failed[t] = true
+			changes = append(changes, change)
+			continue
+		}
+		if busy[owner] == nil {
+			busy[owner] = make(map[time.Time]bool)
+		}
+		first, last, ok := allocate(owner, earliest, days, options.Calendar, busy)
+		if !ok {
+			change.Violations = []string{fmt.Sprintf("%s has not %d free days within %d days from %s",
+				owner, days, maxScheduleDays, earliest.Format(DateLayout))}
+			failed[t] = true
+			changes = append(changes, change)
+			continue
+		}
+		t.Start, t.End = first, last.AddDate(0, 0, 1)
+
+		change.Start, change.End = first, last
+		change.Moved = change.OldStart != first.Format(DateLayout) || change.OldEnd != last.Format(DateLayout)
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// GetSchedule computes the dates of the tasks in the gantt. When apply is true, the dates of the
+// tasks that moved are saved.
+func GetSchedule(project *core.Project, from time.Time, apply bool) ([]Change, error) {
+	tasks, err := GetTasks(project)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := AutoSchedule(tasks, ScheduleOptions{
+		From:         from,
+		Calendar:     core.GetCalendar(project),
+		DaysPerPoint: project.Config.Public.DaysPerPoint,
+	})
+	if !apply {
+		return changes, nil
+	}
+
+	for _, change := range changes {
+		if !change.Moved {
+			continue
+		}
+		task, err := core.GetTask(project, change.Board, change.Name)
+		if core.IsErr(err, "cannot read task %s/%s for scheduling", change.Board, change.Name) {
+			return changes, err
+		}
+		task.Properties["Start"] = change.Start.Format(DateLayout)
+		task.Properties["End"] = change.End.Format(DateLayout)
+		if err := core.SetTask(project, change.Board, change.Name, &task); err != nil {
+			return changes, err
+		}
+		core.QueueReIndex(project, change.Board, change.Name)
+	}
+	return changes, nil
+}
@@ -0,0 +1,77 @@
+package gantt
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAutoSchedule(t *testing.T) {
+	design := newTask("1.Design", "", "", "")
+	design.Task.Properties["Points"] = "2"
+	design.Task.Properties["Owner"] = "@bob"
+	build := newTask("2.Build", "", "", "1")
+	build.Task.Properties["Duration"] = "3d"
+	build.Task.Properties["Owner"] = "@alice"
+	docs := newTask("3.Docs", "", "", "")
+	docs.Task.Properties["Points"] = "1"
+	docs.Task.Properties["Owner"] = "@bob"
+	review := newTask("4.Review", "2021-03-01", "2021-03-02", "")
+	review.Task.Properties["Owner"] = "@alice"
+	review.Task.Properties["Pinned"] = "true"
+	review.Task.Properties["Points"] = "5"
+
+	// Friday, 5th March 2021
+	changes := AutoSchedule([]*Task{design, build, docs, review}, ScheduleOptions{
+		From: time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC),
+	})
+	dates := make(map[string]string)
+	for _, change := range changes {
+		dates[change.Name] = change.Start.Format(DateLayout) + " " + change.End.Format(DateLayout)
+	}
+
+	assert.Equal(t, "2021-03-05 2021-03-08", dates["1.Design"])
+	assert.Equal(t, "2021-03-09 2021-03-11", dates["2.Build"])
+	assert.Equal(t, "2021-03-09 2021-03-09", dates["3.Docs"])
+	assert.Equal(t, "2021-03-01 2021-03-02", dates["4.Review"])
+}
+
+// never is a calendar without working days
+type never struct{}
+
+func (never) IsWorkingDay(string, time.Time) bool { return false }
+
+func TestAutoScheduleViolations(t *testing.T) {
+	loop1 := newTask("1.Loop", "", "", "2")
+	loop2 := newTask("2.Loop", "", "", "1")
+	busy := newTask("3.Busy", "", "", "")
+	after := newTask("4.After", "", "", "3")
+	for _, task := range []*Task{loop1, loop2, busy, after} {
+		task.Task.Properties["Duration"] = "1"
+	}
+
+	changes := AutoSchedule([]*Task{loop1, loop2, busy, after}, ScheduleOptions{
+		From:     time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC),
+		Calendar: never{},
+	})
+	assert.Len(t, changes, 4)
+	for _, change := range changes {
+		assert.Len(t, change.Violations, 1, change.Name)
+		assert.False(t, change.Moved)
+		assert.True(t, change.End.IsZero())
+	}
+}
+
+func TestAutoScheduleNoEstimate(t *testing.T) {
+	idea := newTask("1.Idea", "", "", "")
+	build := newTask("2.Build", "", "", "1")
+	build.Task.Properties["Duration"] = "2"
+
+	changes := AutoSchedule([]*Task{idea, build}, ScheduleOptions{
+		From: time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC),
+	})
+	assert.Len(t, changes, 2)
+	assert.Equal(t, []string{"no estimate and no dates"}, changes[0].Violations)
+	assert.Equal(t, []string{"depends on unscheduled task 1.Idea"}, changes[1].Violations)
+	assert.True(t, changes[1].End.IsZero())
+}
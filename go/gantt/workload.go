@@ -0,0 +1,194 @@
+package gantt
+
+import (
+	"almost-scrum/core"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Periods of the buckets in the workload
+const (
+	PeriodDay  = "day"
+	PeriodWeek = "week"
+)
+
+// ErrInvalidPeriod is returned when the period of the workload is not day or week
+var ErrInvalidPeriod = errors.New("invalid period")
+
+// Allocation is the effort in days of a task in a bucket
+type Allocation struct {
+	Board  string  `json:"board"`
+	Name   string  `json:"name"`
+	Effort float64 `json:"effort"`
+}
+
+// Load is the effort allocated to a user in a day or a week against the working days of the user
+type Load struct {
+	Start         time.Time    `json:"start"`
+	Allocated     float64      `json:"allocated"`
+	Capacity      float64      `json:"capacity"`
+	Overallocated bool         `json:"overallocated"`
+	Tasks         []Allocation `json:"tasks"`
+}
+
+// UserLoad is the load of a user in each bucket of the workload
+type UserLoad struct {
+	User          string `json:"user"`
+	Loads         []Load `json:"loads"`
+	Overallocated bool   `json:"overallocated"`
+}
+
+// Workload is the allocation of the users from From (included) to To (excluded)
+type Workload struct {
+	From   time.Time  `json:"from"`
+	To     time.Time  `json:"to"`
+	Period string     `json:"period"`
+	Users  []UserLoad `json:"users"`
+}
+
+// WorkloadOptions configures the workload computation. Users are included even when they have
+// no tasks.
+type WorkloadOptions struct {
+	From         time.Time
+	To           time.Time
+	Period       string
+	Calendar     Calendar
+	DaysPerPoint float64
+	Users        []string
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// getBucket returns the first day of the bucket that contains day
+func getBucket(day time.Time, period string) time.Time {
+	if period == PeriodWeek {
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	}
+	return day
+}
+
+// ComputeWorkload spreads the effort of each task on the working days of its owner between Start
+// and End. The effort comes from Duration or Points; tasks without estimate take the whole days.
+func ComputeWorkload(tasks []*Task, options WorkloadOptions) (Workload, error) {
+	if options.Period == "" {
+		options.Period = PeriodDay
+	}
+	if options.Period != PeriodDay && options.Period != PeriodWeek {
+		return Workload{}, ErrInvalidPeriod
+	}
+	if options.Calendar == nil {
+		options.Calendar = Weekdays{}
+	}
+	if options.DaysPerPoint <= 0 {
+		options.DaysPerPoint = 1
+	}
+	from, to := getBucket(truncateDay(options.From), options.Period), truncateDay(options.To)
+	workload := Workload{From: from, To: to, Period: options.Period, Users: []UserLoad{}}
+
+	loads := make(map[string]map[time.Time]*Load)
+	addUser := func(user string) map[time.Time]*Load {
+		if loads[user] == nil {
+			loads[user] = make(map[time.Time]*Load)
+			for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+				bucket := getBucket(day, options.Period)
+				if loads[user][bucket] == nil {
+					loads[user][bucket] = &Load{Start: bucket, Tasks: []Allocation{}}
+				}
+				if options.Calendar.IsWorkingDay(user, day) {
+					loads[user][bucket].Capacity++
+				}
+			}
+		}
+		return loads[user]
+	}
+	for _, user := range options.Users {
+		addUser(strings.TrimPrefix(user, "@"))
+	}
+
+	for _, t := range tasks {
+		owner := strings.TrimPrefix(strings.TrimSpace(t.Task.Properties["Owner"]), "@")
+		start, ok1 := parseDate(t.Task.Properties["Start"])
+		end, ok2 := parseEnd(t.Task.Properties["End"])
+		if owner == "" || !ok1 || !ok2 {
+			continue
+		}
+		start, end = truncateDay(start), truncateDay(end)
+		if !end.After(start) {
+			end = start.AddDate(0, 0, 1)
+		}
+		if !end.After(from) || !start.Before(to) {
+			continue
+		}
+
+		var workingDays []time.Time
+		for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+			if options.Calendar.IsWorkingDay(owner, day) {
+				workingDays = append(workingDays, day)
+			}
+		}
+		if len(workingDays) == 0 {
+			continue
+		}
+		daily := 1.0
+		if days, estimated := getDays(t, options.DaysPerPoint); estimated {
+			daily = float64(days) / float64(len(workingDays))
+		}
+
+		userLoads := addUser(owner)
+		for _, day := range workingDays {
+			if day.Before(from) || !day.Before(to) {
+				continue
+			}
+			load := userLoads[getBucket(day, options.Period)]
+			load.Allocated += daily
+			if n := len(load.Tasks); n > 0 && load.Tasks[n-1].Board == t.Board && load.Tasks[n-1].Name == t.Name {
+				load.Tasks[n-1].Effort += daily
+			} else {
+				load.Tasks = append(load.Tasks, Allocation{Board: t.Board, Name: t.Name, Effort: daily})
+			}
+		}
+	}
+
+	for user, userLoads := range loads {
+		userLoad := UserLoad{User: user, Loads: make([]Load, 0, len(userLoads))}
+		for _, load := range userLoads {
+			load.Allocated = round(load.Allocated)
+			for i := range load.Tasks {
+				load.Tasks[i].Effort = round(load.Tasks[i].Effort)
+			}
+			load.Overallocated = load.Allocated > load.Capacity
+			userLoad.Overallocated = userLoad.Overallocated || load.Overallocated
+			userLoad.Loads = append(userLoad.Loads, *load)
+		}
+		sort.Slice(userLoad.Loads, func(i, j int) bool {
+			return userLoad.Loads[i].Start.Before(userLoad.Loads[j].Start)
+		})
+		workload.Users = append(workload.Users, userLoad)
+	}
+	sort.Slice(workload.Users, func(i, j int) bool {
+		return workload.Users[i].User < workload.Users[j].User
+	})
+	return workload, nil
+}
+
+// GetWorkload returns the allocation of the users of the project in the calendar of the project
+func GetWorkload(project *core.Project, from time.Time, to time.Time, period string) (Workload, error) {
+	tasks, err := GetTasks(project)
+	if err != nil {
+		return Workload{}, err
+	}
+	return ComputeWorkload(tasks, WorkloadOptions{
+		From:         from,
+		To:           to,
+		Period:       period,
+		Calendar:     core.GetCalendar(project),
+		DaysPerPoint: project.Config.Public.DaysPerPoint,
+		Users:        core.GetUserList(project),
+	})
+}
@@ -0,0 +1,44 @@
+package gantt
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestComputeWorkload(t *testing.T) {
+	tasks := []*Task{
+		newTask("1.Design", "2021-03-01", "2021-03-02", ""),
+		newTask("2.Build", "2021-03-02", "2021-03-05", ""),
+		newTask("3.Docs", "2021-03-01", "2021-03-04", ""),
+	}
+	tasks[0].Task.Properties["Owner"] = "@bob"
+	tasks[1].Task.Properties["Owner"] = "@bob"
+	tasks[1].Task.Properties[DurationProperty] = "2"
+	tasks[2].Task.Properties["Owner"] = "@alice"
+
+	from := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	workload, err := ComputeWorkload(tasks, WorkloadOptions{
+		From: from, To: from.AddDate(0, 0, 7), Users: []string{"carol"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"alice", "bob", "carol"},
+		[]string{workload.Users[0].User, workload.Users[1].User, workload.Users[2].User})
+
+	bob := workload.Users[1]
+	assert.True(t, bob.Overallocated)
+	assert.Equal(t, 1.0, bob.Loads[0].Allocated)
+	assert.Equal(t, 1.5, bob.Loads[1].Allocated)
+	assert.True(t, bob.Loads[1].Overallocated)
+	assert.Len(t, bob.Loads[1].Tasks, 2)
+	assert.Equal(t, 0.0, bob.Loads[5].Capacity)
+	assert.False(t, workload.Users[0].Overallocated)
+
+	weekly, err := ComputeWorkload(tasks, WorkloadOptions{
+		From: from.AddDate(0, 0, 2), To: from.AddDate(0, 0, 7), Period: PeriodWeek,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, weekly.Users[1].Loads, 1)
+	assert.Equal(t, 5.0, weekly.Users[1].Loads[0].Capacity)
+	assert.Equal(t, 4.0, weekly.Users[1].Loads[0].Allocated)
+}
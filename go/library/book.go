@@ -9,6 +9,7 @@ import (
 	"github.com/gabriel-vasile/mimetype"
 	"github.com/russross/blackfriday/v2"
 	"github.com/sirupsen/logrus"
+	"html"
 	"io/ioutil"
 	"path/filepath"
 	"regexp"
@@ -32,7 +33,9 @@ func embedImage(file string) (string, error) {
 
 var reImage = regexp.MustCompile(`<img\s+src="~library/([^#]+)#([^"]+)"`)
 
-func replaceImg(html string, libraryFolder string) string {
+// replaceImg sets the source of images that link to the library. The function src returns the new
+// source for a file in the library, e.g. a data URL.
+func replaceImg(html string, libraryFolder string, src func(file string) (string, error)) string {
 	var output bytes.Buffer
 	pos := 0
 	matches := reImage.FindAllStringSubmatchIndex(html, -1)
@@ -43,7 +46,7 @@ func replaceImg(html string, libraryFolder string) string {
 		alt := html[match[4]:match[5]]
 		opts := strings.Split(alt, ",")
 
-		imageSrc, err := embedImage(filepath.Join(libraryFolder, loc))
+		imageSrc, err := src(filepath.Join(libraryFolder, loc))
 		if err != nil {
 			output.WriteString(html[pos:match[1]])
 			pos = match[1]
@@ -82,7 +85,7 @@ func replaceImg(html string, libraryFolder string) string {
 	return output.String()
 }
 
-func ExportMarkdownToHTML(file string, libraryFolder string) (string, error) {
+func renderMarkdown(file string, libraryFolder string, src func(file string) (string, error)) (string, error) {
 	input, err := ioutil.ReadFile(file)
 	if err != nil {
 		return "", err
@@ -93,14 +96,21 @@ func ExportMarkdownToHTML(file string, libraryFolder string) (string, error) {
 			blackfriday.HardLineBreak|
 			blackfriday.NoEmptyLineBeforeBlock,
 	))
-	return replaceImg(string(body), libraryFolder), nil
+	return replaceImg(string(body), libraryFolder, src), nil
 }
 
+func ExportMarkdownToHTML(file string, libraryFolder string) (string, error) {
+	return renderMarkdown(file, libraryFolder, embedImage)
+}
+
+// BookSettings are the options for the generation of a book. When Numbered is true, chapters and
+// headings are numbered like 2.1.
 type BookSettings struct {
 	Title    string   `json:"title"`
 	Subtitle string   `json:"subtitle"`
 	Authors  string   `json:"authors"`
 	Styles   []string `json:"styles"`
+	Numbered bool     `json:"numbered"`
 }
 
 //var whitespaceRe = regexp.MustCompile(`\s+`)
@@ -116,14 +126,51 @@ func addStyle(style string, output *bytes.Buffer) {
 	output.Write(css)
 }
 
-var sectionTitleRe = regexp.MustCompile(`\d*\.?\s*(.*)\.(pg)|(md)$`)
+var sectionTitleRe = regexp.MustCompile(`^\d*\.?\s*(.*)\.md$`)
+
+// chapter is a markdown file in the folder of a book rendered to HTML. Name is the file name without
+// extension. The other fields are set by prepareBook: label is the title with the number, href the
+// document that contains the chapter and body the HTML with numbers and links.
+type chapter struct {
+	name  string
+	title string
+	html  string
+	id    string
+	label string
+	href  string
+	body  string
+}
 
-func CreateBook(project *core.Project, loc string, settings BookSettings) (string, error) {
-	var output bytes.Buffer
+// getChapters renders the markdown files in the folder of a book, in the order of their names. Numbers at
+// the beginning of the names set the order and are not part of the titles.
+func getChapters(project *core.Project, loc string, src func(file string) (string, error)) ([]chapter, error) {
 	folder := filepath.Join(project.Path, core.ProjectLibraryFolder, loc)
 	files, err := ioutil.ReadDir(folder)
 	if err != nil {
 		logrus.Errorf("cannot open library folder %s: %v", folder, err)
+		return nil, err
+	}
+
+	var chapters []chapter
+	libraryFolder := filepath.Join(project.Path, core.ProjectLibraryFolder, "")
+	for _, file := range files {
+		name := file.Name()
+		match := sectionTitleRe.FindStringSubmatch(name)
+		if file.IsDir() || len(match) != 2 {
+			continue
+		}
+		part, err := renderMarkdown(filepath.Join(folder, name), libraryFolder, src)
+		if err == nil {
+			chapters = append(chapters, chapter{name: strings.TrimSuffix(name, ".md"), title: match[1], html: part})
+		}
+	}
+	return chapters, nil
+}
+
+func CreateBook(project *core.Project, loc string, settings BookSettings) (string, error) {
+	var output bytes.Buffer
+	chapters, err := getChapters(project, loc, embedImage)
+	if err != nil {
 		return "", err
 	}
 
@@ -144,21 +191,26 @@ func CreateBook(project *core.Project, loc string, settings BookSettings) (strin
 	}
 	output.WriteString(`</section>`)
 
-	libraryFolder := filepath.Join(project.Path, core.ProjectLibraryFolder, "")
-	for idx, file := range files {
-		name := file.Name()
-		match := sectionTitleRe.FindStringSubmatch(name)
-		if len(match) == 4 {
-			sectionTitle := match[1]
-			part, err := ExportMarkdownToHTML(filepath.Join(folder, name), libraryFolder)
-			if err == nil {
-				output.WriteString(
-					fmt.Sprintf(`<section id="section-%d"><div class="sectionTitle">%s</div>`,
-						idx, sectionTitle))
-				output.WriteString(part)
-				output.WriteString(`</section>`)
-			}
-		}
+	content, err := prepareBook(chapters, settings, func(int) string { return "" })
+	if err != nil {
+		return "", err
+	}
+	output.WriteString(`<section class="toc">`)
+	output.WriteString(content.getTOC())
+	output.WriteString(`</section>`)
+
+	for _, chapter := range content.chapters {
+		output.WriteString(
+			fmt.Sprintf(`<section id="%s"><div class="sectionTitle">%s</div>`,
+				chapter.id, html.EscapeString(chapter.label)))
+		output.WriteString(chapter.body)
+		output.WriteString(`</section>`)
+	}
+
+	if len(content.terms) > 0 {
+		output.WriteString(`<section class="index">`)
+		output.WriteString(content.getIndex())
+		output.WriteString(`</section>`)
 	}
 
 	output.WriteString("</body></html>")
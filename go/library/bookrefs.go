@@ -0,0 +1,460 @@
+package library
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// tocEntry is an item of the table of contents: a chapter or a heading in a chapter
+type tocEntry struct {
+	title    string
+	href     string
+	level    int
+	children []*tocEntry
+}
+
+// bookTarget is a place in the book that cross-references and the index link to
+type bookTarget struct {
+	href  string
+	label string
+}
+
+// bookContent is the content of a book ready to be written as HTML or EPUB
+type bookContent struct {
+	chapters []chapter
+	toc      []*tocEntry
+	terms    []string
+	index    map[string][]bookTarget
+}
+
+var (
+	bookRefRe      = regexp.MustCompile(`\[\[([^\]|]+)(?:\|([^\]]+))?\]\]|\{\{([^}]+)\}\}`)
+	tableCaptionRe = regexp.MustCompile(`^Table:\s*(.*?)\s*(?:\{#([^}]+)\})?$`)
+)
+
+func getNodeText(n *xhtml.Node) string {
+	var text strings.Builder
+	var walk func(*xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.TextNode {
+			text.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.TrimSpace(text.String())
+}
+
+func getAttr(n *xhtml.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *xhtml.Node, key string, val string) {
+	for i, attr := range n.Attr {
+		if attr.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, xhtml.Attribute{Key: key, Val: val})
+}
+
+func newElement(a atom.Atom, attrs ...string) *xhtml.Node {
+	n := &xhtml.Node{Type: xhtml.ElementNode, Data: a.String(), DataAtom: a}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		n.Attr = append(n.Attr, xhtml.Attribute{Key: attrs[i], Val: attrs[i+1]})
+	}
+	return n
+}
+
+func newText(text string) *xhtml.Node {
+	return &xhtml.Node{Type: xhtml.TextNode, Data: text}
+}
+
+// slug returns an anchor for a title, e.g. "Getting Started" becomes getting-started
+func slug(title string) string {
+	var s strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && s.Len() > 0 {
+				s.WriteByte('-')
+			}
+			s.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return s.String()
+}
+
+// previousElement returns the element before a node, skipping blank text
+func previousElement(n *xhtml.Node) *xhtml.Node {
+	for p := n.PrevSibling; p != nil; p = p.PrevSibling {
+		if p.Type == xhtml.ElementNode {
+			return p
+		}
+		if p.Type == xhtml.TextNode && strings.TrimSpace(p.Data) != "" {
+			return nil
+		}
+	}
+	return nil
+}
+
+// getFigureImage returns the image when a paragraph contains only an image with an alternate text
+func getFigureImage(p *xhtml.Node) *xhtml.Node {
+	var img *xhtml.Node
+	for c := p.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == xhtml.ElementNode && c.DataAtom == atom.Img && img == nil:
+			img = c
+		case c.Type == xhtml.TextNode && strings.TrimSpace(c.Data) == "":
+		default:
+			return nil
+		}
+	}
+	if img == nil || getAttr(img, "alt") == "" {
+		return nil
+	}
+	return img
+}
+
+// nestEntries places the headings of a chapter under the chapter and h2 under the previous h1
+func nestEntries(chapter *tocEntry, headings []*tocEntry) {
+	stack := []*tocEntry{chapter}
+	for _, heading := range headings {
+		for len(stack) > 1 && stack[len(stack)-1].level >= heading.level {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, heading)
+		stack = append(stack, heading)
+	}
+}
+
+func writeNavList(entries []*tocEntry, output *strings.Builder) {
+	output.WriteString("<ol>")
+	for _, entry := range entries {
+		output.WriteString(fmt.Sprintf(`<li><a href="%s">%s</a>`, html.EscapeString(entry.href),
+			html.EscapeString(entry.title)))
+		if len(entry.children) > 0 {
+			writeNavList(entry.children, output)
+		}
+		output.WriteString("</li>")
+	}
+	output.WriteString("</ol>")
+}
+
+// bookBuilder keeps the state while the chapters of a book are prepared
+type bookBuilder struct {
+	settings BookSettings
+	content  *bookContent
+	targets  map[string]map[string]bookTarget
+	figures  int
+	tables   int
+	terms    int
+}
+
+func (b *bookBuilder) addTarget(c *chapter, anchor string, target bookTarget) {
+	for _, key := range []string{c.name, c.title, slug(c.title)} {
+		key = strings.ToLower(key)
+		if b.targets[key] == nil {
+			b.targets[key] = make(map[string]bookTarget)
+		}
+		if _, found := b.targets[key][anchor]; !found {
+			b.targets[key][anchor] = target
+		}
+	}
+}
+
+// numberChapter gives ids and numbers to headings, figures and tables and returns the headings for the
+// table of contents
+func (b *bookBuilder) numberChapter(idx int, c *chapter, body *xhtml.Node) []*tocEntry {
+	var headings, paragraphs, tables []*xhtml.Node
+	var walk func(*xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.ElementNode {
+			switch n.DataAtom {
+			case atom.H1, atom.H2, atom.H3:
+				headings = append(headings, n)
+			case atom.P:
+				paragraphs = append(paragraphs, n)
+			case atom.Table:
+				tables = append(tables, n)
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(body)
+
+	var entries []*tocEntry
+	var counters [3]int
+	ids := make(map[string]bool)
+	for _, n := range headings {
+		level := int(n.Data[1] - '0')
+		counters[level-1]++
+		for i := level; i < len(counters); i++ {
+			counters[i] = 0
+		}
+
+		title := getNodeText(n)
+		anchor := getAttr(n, "id")
+		if anchor == "" {
+			anchor = slug(title)
+		}
+		for base, i := anchor, 2; ids[anchor]; i++ {
+			anchor = fmt.Sprintf("%s-%d", base, i)
+		}
+		ids[anchor] = true
+		id := c.id + "-" + anchor
+		setAttr(n, "id", id)
+
+		if b.settings.Numbered {
+			number := fmt.Sprint(idx + 1)
+			for _, counter := range counters[0:level] {
+				number = fmt.Sprintf("%s.%d", number, counter)
+			}
+			n.InsertBefore(newElement(atom.Span, "class", "number"), n.FirstChild)
+			n.FirstChild.AppendChild(newText(number + " "))
+			title = number + " " + title
+		}
+		target := bookTarget{href: c.href + "#" + id, label: title}
+		b.addTarget(c, strings.ToLower(anchor), target)
+		if level < 3 {
+			entries = append(entries, &tocEntry{title: title, href: target.href, level: level})
+		}
+	}
+
+	for _, p := range paragraphs {
+		img := getFigureImage(p)
+		if img == nil || p.Parent == nil {
+			continue
+		}
+		b.figures++
+		anchor := slug(getAttr(img, "title"))
+		if anchor == "" {
+			anchor = fmt.Sprintf("figure-%d", b.figures)
+		}
+		label := fmt.Sprintf("Figure %d", b.figures)
+		figure := newElement(atom.Figure, "id", c.id+"-"+anchor)
+		caption := newElement(atom.Figcaption)
+		caption.AppendChild(newText(fmt.Sprintf("%s: %s", label, getAttr(img, "alt"))))
+		p.RemoveChild(img)
+		figure.AppendChild(img)
+		figure.AppendChild(caption)
+		p.Parent.InsertBefore(figure, p)
+		p.Parent.RemoveChild(p)
+		b.addTarget(c, anchor, bookTarget{href: c.href + "#" + c.id + "-" + anchor, label: label})
+	}
+
+	for _, table := range tables {
+		p := previousElement(table)
+		if p == nil || p.DataAtom != atom.P {
+			continue
+		}
+		match := tableCaptionRe.FindStringSubmatch(getNodeText(p))
+		if match == nil {
+			continue
+		}
+		b.tables++
+		anchor := slug(match[2])
+		if anchor == "" {
+			anchor = fmt.Sprintf("table-%d", b.tables)
+		}
+		label := fmt.Sprintf("Table %d", b.tables)
+		setAttr(table, "id", c.id+"-"+anchor)
+		caption := newElement(atom.Caption)
+		caption.AppendChild(newText(fmt.Sprintf("%s: %s", label, match[1])))
+		table.InsertBefore(caption, table.FirstChild)
+		if p.Parent != nil {
+			p.Parent.RemoveChild(p)
+		}
+		b.addTarget(c, anchor, bookTarget{href: c.href + "#" + c.id + "-" + anchor, label: label})
+	}
+	return entries
+}
+
+// resolve returns the target of a cross-reference like chapter#anchor or #anchor for the current chapter
+func (b *bookBuilder) resolve(c *chapter, ref string) (bookTarget, bool) {
+	parts := strings.SplitN(strings.TrimSpace(ref), "#", 2)
+	key := strings.ToLower(strings.TrimSpace(parts[0]))
+	if key == "" {
+		key = strings.ToLower(c.name)
+	}
+	anchor := ""
+	if len(parts) == 2 {
+		anchor = strings.ToLower(strings.TrimSpace(parts[1]))
+	}
+	target, found := b.targets[key][anchor]
+	if !found && anchor != "" {
+		target, found = b.targets[key][slug(anchor)]
+	}
+	return target, found
+}
+
+// replaceRefs replaces cross-references with links and tagged terms with anchors for the index
+func (b *bookBuilder) replaceRefs(c *chapter, body *xhtml.Node) {
+	var texts []*xhtml.Node
+	var walk func(*xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		if n.Type == xhtml.ElementNode && (n.DataAtom == atom.Code || n.DataAtom == atom.Pre || n.DataAtom == atom.A) {
+			return
+		}
+		if n.Type == xhtml.TextNode && bookRefRe.MatchString(n.Data) {
+			texts = append(texts, n)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(body)
+
+	for _, n := range texts {
+		pos := 0
+		for _, match := range bookRefRe.FindAllStringSubmatchIndex(n.Data, -1) {
+			if match[0] > pos {
+				n.Parent.InsertBefore(newText(n.Data[pos:match[0]]), n)
+			}
+			pos = match[1]
+
+			if match[6] >= 0 {
+				term := strings.TrimSpace(n.Data[match[6]:match[7]])
+				b.terms++
+				id := fmt.Sprintf("%s-term-%d", c.id, b.terms)
+				span := newElement(atom.Span, "class", "term", "id", id)
+				span.AppendChild(newText(term))
+				n.Parent.InsertBefore(span, n)
+
+				key := strings.ToLower(term)
+				if _, found := b.content.index[key]; !found {
+					b.content.terms = append(b.content.terms, term)
+				}
+				b.content.index[key] = append(b.content.index[key], bookTarget{href: c.href + "#" + id, label: c.label})
+				continue
+			}
+
+			ref := n.Data[match[2]:match[3]]
+			target, found := b.resolve(c, ref)
+			if !found {
+				span := newElement(atom.Span, "class", "brokenRef")
+				span.AppendChild(newText(n.Data[match[0]:match[1]]))
+				n.Parent.InsertBefore(span, n)
+				continue
+			}
+			label := target.label
+			if match[4] >= 0 {
+				label = strings.TrimSpace(n.Data[match[4]:match[5]])
+			}
+			a := newElement(atom.A, "class", "ref", "href", target.href)
+			a.AppendChild(newText(label))
+			n.Parent.InsertBefore(a, n)
+		}
+		if pos < len(n.Data) {
+			n.Parent.InsertBefore(newText(n.Data[pos:]), n)
+		}
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// prepareBook numbers headings, figures and tables, resolves the cross-references between chapters and
+// collects the table of contents and the index. The function href returns the document of each chapter,
+// which is empty when the book is a single page. The body of the chapters is set as well formed XHTML.
+func prepareBook(chapters []chapter, settings BookSettings, href func(idx int) string) (*bookContent, error) {
+	b := bookBuilder{
+		settings: settings,
+		content:  &bookContent{chapters: chapters, index: make(map[string][]bookTarget)},
+		targets:  make(map[string]map[string]bookTarget),
+	}
+
+	bodies := make([]*xhtml.Node, len(chapters))
+	for i := range chapters {
+		c := &chapters[i]
+		c.id = fmt.Sprintf("section-%d", i)
+		c.href = href(i)
+		c.label = c.title
+		if settings.Numbered {
+			c.label = fmt.Sprintf("%d %s", i+1, c.title)
+		}
+
+		body := newElement(atom.Body)
+		nodes, err := xhtml.ParseFragment(strings.NewReader(c.html), body)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			body.AppendChild(n)
+		}
+		bodies[i] = body
+
+		entry := &tocEntry{title: c.label, href: c.href + "#" + c.id}
+		b.addTarget(c, "", bookTarget{href: entry.href, label: c.label})
+		nestEntries(entry, b.numberChapter(i, c, body))
+		b.content.toc = append(b.content.toc, entry)
+	}
+
+	for i := range chapters {
+		c := &chapters[i]
+		b.replaceRefs(c, bodies[i])
+
+		var output bytes.Buffer
+		for n := bodies[i].FirstChild; n != nil; n = n.NextSibling {
+			if err := xhtml.Render(&output, n); err != nil {
+				return nil, err
+			}
+		}
+		c.body = output.String()
+	}
+
+	sort.Slice(b.content.terms, func(i, j int) bool {
+		return strings.ToLower(b.content.terms[i]) < strings.ToLower(b.content.terms[j])
+	})
+	return b.content, nil
+}
+
+// getTOC returns the table of contents of the book
+func (content *bookContent) getTOC() string {
+	var toc strings.Builder
+	toc.WriteString(`<h1>Contents</h1>`)
+	writeNavList(content.toc, &toc)
+	return toc.String()
+}
+
+// getIndex returns the index of the tagged terms with links to the chapters where they appear
+func (content *bookContent) getIndex() string {
+	var index strings.Builder
+	index.WriteString(`<h1>Index</h1><dl class="index">`)
+	for _, term := range content.terms {
+		index.WriteString(fmt.Sprintf(`<dt>%s</dt><dd>`, html.EscapeString(term)))
+		seen := make(map[string]bool)
+		var links []string
+		for _, target := range content.index[strings.ToLower(term)] {
+			if !seen[target.label] {
+				seen[target.label] = true
+				links = append(links, fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(target.href),
+					html.EscapeString(target.label)))
+			}
+		}
+		index.WriteString(strings.Join(links, ", "))
+		index.WriteString(`</dd>`)
+	}
+	index.WriteString(`</dl>`)
+	return index.String()
+}
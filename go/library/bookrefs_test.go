@@ -0,0 +1,36 @@
+package library
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareBook(t *testing.T) {
+	chapters := []chapter{
+		{name: "1. Intro", title: "Intro", html: `<h1>Welcome</h1><p>See [[Usage#run]] and [[usage#commands|the commands]], ` +
+			`not [[Missing]].</p><p><img src="a.png" alt="The logo" title="logo"/></p><p>The {{scrum}} board.</p>`},
+		{name: "2. Usage", title: "Usage", html: `<h1>Usage</h1><h2 id="run">Run it</h2><p>Table: Commands {#commands}</p>` +
+			`<table><tr><td>ls</td></tr></table><p>Back to [[Intro#logo]] in {{Scrum}}.</p>`},
+	}
+
+	content, err := prepareBook(chapters, BookSettings{Numbered: true}, func(int) string { return "" })
+	assert.Nil(t, err)
+
+	intro, usage := content.chapters[0].body, content.chapters[1].body
+	assert.Contains(t, intro, `<h1 id="section-0-welcome"><span class="number">1.1 </span>Welcome</h1>`)
+	assert.Contains(t, intro, `<a class="ref" href="#section-1-run">2.1.1 Run it</a>`)
+	assert.Contains(t, intro, `<a class="ref" href="#section-1-commands">the commands</a>`)
+	assert.Contains(t, intro, `<span class="brokenRef">[[Missing]]</span>`)
+	assert.Contains(t, intro, `<figure id="section-0-logo"><img src="a.png" alt="The logo" title="logo"/>`+
+		`<figcaption>Figure 1: The logo</figcaption></figure>`)
+	assert.Contains(t, usage, `<table id="section-1-commands"><caption>Table 1: Commands</caption>`)
+	assert.NotContains(t, usage, "Table: Commands")
+	assert.Contains(t, usage, `<a class="ref" href="#section-0-logo">Figure 1</a>`)
+
+	assert.Equal(t, "1 Intro", content.toc[0].title)
+	assert.Equal(t, "2.1 Usage", content.toc[1].children[0].title)
+	assert.Equal(t, []string{"scrum"}, content.terms)
+	assert.Contains(t, content.getIndex(), `<dt>scrum</dt><dd><a href="#section-0-term-1">1 Intro</a>, `+
+		`<a href="#section-1-term-2">2 Usage</a></dd>`)
+}
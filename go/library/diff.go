@@ -0,0 +1,283 @@
+package library
+
+import (
+	"almost-scrum/core"
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// DiffContext is the number of unchanged lines around the changes in a diff
+const DiffContext = 3
+
+// Diff is the comparison between two versions of a document. Unified is in the unified diff
+// format; HTML shows the same hunks with the changed words highlighted with ins and del.
+type Diff struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	Unified string `json:"unified"`
+	HTML    string `json:"html"`
+}
+
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string
+	old  int
+	new  int
+}
+
+var wordToken = regexp.MustCompile(`\s+|[\pL\p{Mn}\pN_]+|.`)
+
+// getVersionPath returns the location of a version of a document, in the library or in the archive.
+// Names outside the library and the archive are not found.
+func getVersionPath(project *core.Project, name string) (string, error) {
+	name = path.Clean("/" + filepath.ToSlash(name))
+	for _, resolve := range []func(*core.Project, string) (string, error){AbsPath, ArchivePath} {
+		root, err := resolve(project, "")
+		if err != nil {
+			continue
+		}
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if !isInside(root, p) {
+			continue
+		}
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", core.ErrNoFound
+}
+
+// surrogateMin and surrogateMax are the runes that cannot be in a string, since they become
+// utf8.RuneError when the diff converts the runes to text
+const surrogateMin, surrogateMax = 0xD800, 0xDFFF
+
+// tokenRune returns the rune of the token with the given index, skipping the surrogates
+func tokenRune(index int) rune {
+	if index >= surrogateMin {
+		return rune(index + surrogateMax - surrogateMin + 1)
+	}
+	return rune(index)
+}
+
+// tokenIndex returns the index of the token with the given rune
+func tokenIndex(r rune) int {
+	if r > surrogateMax {
+		return int(r) - (surrogateMax - surrogateMin + 1)
+	}
+	return int(r)
+}
+
+// diffTokens compares two sequences of tokens, i.e. lines or words. Each distinct token is mapped to
+// a rune so that the diff runs on the sequences and not on the characters. The text of the returned
+// diffs is the list of runes, which tokenIndex converts to the indexes in tokens.
+func diffTokens(tokens1, tokens2 []string) ([]diffmatchpatch.Diff, []string) {
+	runes := make(map[string]rune)
+	var tokens []string
+	toRunes := func(seq []string) []rune {
+		var rs []rune
+		for _, token := range seq {
+			r, found := runes[token]
+			if !found {
+				r = tokenRune(len(tokens))
+				runes[token] = r
+				tokens = append(tokens, token)
+			}
+			rs = append(rs, r)
+		}
+		return rs
+	}
+
+	dmp := diffmatchpatch.New()
+	return dmp.DiffMainRunes(toRunes(tokens1), toRunes(tokens2), false), tokens
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// getLines compares the text and returns the lines with the operation and their numbers in the
+// old and the new text
+func getLines(text1, text2 string) []diffLine {
+	diffs, tokens := diffTokens(splitLines(text1), splitLines(text2))
+
+	var lines []diffLine
+	old, new := 1, 1
+	for _, diff := range diffs {
+		for _, r := range diff.Text {
+			lines = append(lines, diffLine{op: diff.Type, text: tokens[tokenIndex(r)], old: old, new: new})
+			if diff.Type != diffmatchpatch.DiffInsert {
+				old++
+			}
+			if diff.Type != diffmatchpatch.DiffDelete {
+				new++
+			}
+		}
+	}
+	return lines
+}
+
+// getHunks groups the changed lines with their context. It returns the start and the end of each hunk.
+func getHunks(lines []diffLine) [][2]int {
+	var hunks [][2]int
+	for i, line := range lines {
+		if line.op == diffmatchpatch.DiffEqual {
+			continue
+		}
+		start, end := i-DiffContext, i+DiffContext+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if n := len(hunks); n > 0 && start <= hunks[n-1][1] {
+			hunks[n-1][1] = end
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+	return hunks
+}
+
+func writeUnified(diff *Diff, lines []diffLine, hunks [][2]int) {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "--- a%s\n+++ b%s\n", diff.From, diff.To)
+	for _, hunk := range hunks {
+		var oldCount, newCount int
+		for _, line := range lines[hunk[0]:hunk[1]] {
+			if line.op != diffmatchpatch.DiffInsert {
+				oldCount++
+			}
+			if line.op != diffmatchpatch.DiffDelete {
+				newCount++
+			}
+		}
+		first := lines[hunk[0]]
+		_, _ = fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", first.old, oldCount, first.new, newCount)
+		for _, line := range lines[hunk[0]:hunk[1]] {
+			switch line.op {
+			case diffmatchpatch.DiffInsert:
+				b.WriteByte('+')
+			case diffmatchpatch.DiffDelete:
+				b.WriteByte('-')
+			default:
+				b.WriteByte(' ')
+			}
+			b.WriteString(line.text)
+			b.WriteByte('\n')
+		}
+	}
+	diff.Unified = b.String()
+}
+
+// diffWords compares two blocks of text word by word
+func diffWords(text1, text2 string) []diffmatchpatch.Diff {
+	diffs, tokens := diffTokens(wordToken.FindAllString(text1, -1), wordToken.FindAllString(text2, -1))
+	for i, diff := range diffs {
+		var text strings.Builder
+		for _, r := range diff.Text {
+			text.WriteString(tokens[tokenIndex(r)])
+		}
+		diffs[i].Text = text.String()
+	}
+	return diffs
+}
+
+func writeBlock(b *strings.Builder, class string, diffs []diffmatchpatch.Diff, skip diffmatchpatch.Operation,
+	tag string) {
+	_, _ = fmt.Fprintf(b, `<div class="%s">`, class)
+	for _, diff := range diffs {
+		switch diff.Type {
+		case skip:
+		case diffmatchpatch.DiffEqual:
+			b.WriteString(html.EscapeString(diff.Text))
+		default:
+			_, _ = fmt.Fprintf(b, "<%s>%s</%s>", tag, html.EscapeString(diff.Text), tag)
+		}
+	}
+	b.WriteString("</div>")
+}
+
+func writeHTML(diff *Diff, lines []diffLine, hunks [][2]int) {
+	var b strings.Builder
+	b.WriteString(`<div class="diff" style="white-space: pre-wrap">`)
+	for _, hunk := range hunks {
+		first := lines[hunk[0]]
+		_, _ = fmt.Fprintf(&b, `<div class="hunk">@@ -%d +%d @@</div>`, first.old, first.new)
+		for i := hunk[0]; i < hunk[1]; {
+			if lines[i].op == diffmatchpatch.DiffEqual {
+				_, _ = fmt.Fprintf(&b, `<div class="context">%s</div>`, html.EscapeString(lines[i].text))
+				i++
+				continue
+			}
+
+			// removed lines followed by added lines are compared word by word
+			var removed, added []string
+			for ; i < hunk[1] && lines[i].op == diffmatchpatch.DiffDelete; i++ {
+				removed = append(removed, lines[i].text)
+			}
+			for ; i < hunk[1] && lines[i].op == diffmatchpatch.DiffInsert; i++ {
+				added = append(added, lines[i].text)
+			}
+			diffs := diffWords(strings.Join(removed, "\n"), strings.Join(added, "\n"))
+			if len(removed) > 0 {
+				writeBlock(&b, "removed", diffs, diffmatchpatch.DiffInsert, "del")
+			}
+			if len(added) > 0 {
+				writeBlock(&b, "added", diffs, diffmatchpatch.DiffDelete, "ins")
+			}
+		}
+	}
+	b.WriteString("</div>")
+	diff.HTML = b.String()
+}
+
+// CompareText returns the diff between two texts
+func CompareText(from, to string, text1, text2 string) Diff {
+	diff := Diff{From: from, To: to}
+	lines := getLines(text1, text2)
+	for _, line := range lines {
+		switch line.op {
+		case diffmatchpatch.DiffInsert:
+			diff.Added++
+		case diffmatchpatch.DiffDelete:
+			diff.Removed++
+		}
+	}
+
+	hunks := getHunks(lines)
+	writeUnified(&diff, lines, hunks)
+	writeHTML(&diff, lines, hunks)
+	return diff
+}
+
+// CompareVersions returns the diff between two versions of a document, e.g. /Specs/Spec~0.2.docx and
+// /Specs/Spec~0.3.docx. Versions are looked for in the library and in the archive. The text of DOCX,
+// PDF and HTML documents is extracted before the comparison.
+func CompareVersions(project *core.Project, from string, to string) (Diff, error) {
+	var texts [2]string
+	for i, path := range []string{from, to} {
+		p, err := getVersionPath(project, path)
+		if err != nil {
+			return Diff{}, err
+		}
+		text, err := core.ExtractText(p)
+		if err != nil {
+			return Diff{}, err
+		}
+		texts[i] = strings.ReplaceAll(string(text), "\r\n", "\n")
+	}
+	return CompareText(from, to, texts[0], texts[1]), nil
+}
@@ -0,0 +1,57 @@
+package library
+
+import (
+	"almost-scrum/core"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareText(t *testing.T) {
+	text1 := "# Spec\n\nOne\nTwo\nThree\nThe login uses a token\nFour\nFive\nSix\nSeven\nEight\n"
+	text2 := "# Spec\n\nOne\nTwo\nThree\nThe login uses a refresh token\nFour\nFive\nSix\nSeven\nEight\nNine\n"
+
+	diff := CompareText("/Spec~0.2.md", "/Spec~0.3.md", text1, text2)
+	assert.Equal(t, 2, diff.Added)
+	assert.Equal(t, 1, diff.Removed)
+	assert.Equal(t, "--- a/Spec~0.2.md\n+++ b/Spec~0.3.md\n"+
+		"@@ -3,9 +3,10 @@\n One\n Two\n Three\n-The login uses a token\n+The login uses a refresh token\n"+
+		" Four\n Five\n Six\n Seven\n Eight\n+Nine\n", diff.Unified)
+	assert.Contains(t, diff.HTML, "<ins>refresh </ins>")
+	assert.Contains(t, diff.HTML, `<div class="removed">The login uses a token</div>`)
+}
+
+func TestCompareManyLines(t *testing.T) {
+	var lines []string
+	for i := 0; i < 60000; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	text1 := strings.Join(lines, "\n") + "\n"
+	lines[59000] = "changed"
+	text2 := strings.Join(lines, "\n") + "\n"
+
+	diff := CompareText("/Log~0.1.txt", "/Log~0.2.txt", text1, text2)
+	assert.Equal(t, 1, diff.Added)
+	assert.Equal(t, 1, diff.Removed)
+	assert.Contains(t, diff.Unified, "\n-line 59000\n+changed\n")
+}
+
+func TestCompareVersionsOutside(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "ash-diff")
+	defer os.RemoveAll(dir)
+
+	project := &core.Project{Path: filepath.Join(dir, "project")}
+	_ = os.MkdirAll(filepath.Join(project.Path, core.ProjectLibraryFolder), 0755)
+	_ = ioutil.WriteFile(filepath.Join(project.Path, core.ProjectLibraryFolder, "Spec~0.1.txt"), []byte("spec"), 0644)
+	_ = ioutil.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0644)
+
+	_, err := CompareVersions(project, "/Spec~0.1.txt", "../../secret.txt")
+	assert.Equal(t, core.ErrNoFound, err)
+	_, err = CompareVersions(project, "/../../secret.txt", "/Spec~0.1.txt")
+	assert.Equal(t, core.ErrNoFound, err)
+}
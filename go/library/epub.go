@@ -0,0 +1,279 @@
+package library
+
+import (
+	"almost-scrum/core"
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/uuid"
+)
+
+// EPUBMime is the content type of EPUB files
+const EPUBMime = "application/epub+zip"
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// epubImage is an image of the library included in the book
+type epubImage struct {
+	id   string
+	href string
+	mime string
+	file string
+}
+
+type epubBook struct {
+	settings BookSettings
+	id       string
+	language string
+	images   map[string]*epubImage
+	order    []*epubImage
+	chapters []string
+	content  *bookContent
+}
+
+// addImage includes an image of the library in the book and returns its location in the book
+func (b *epubBook) addImage(file string) (string, error) {
+	if image, found := b.images[file]; found {
+		return image.href, nil
+	}
+	mime, err := mimetype.DetectFile(file)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(mime.String(), "image/") {
+		return "", fmt.Errorf("%s is not an image", file)
+	}
+
+	n := len(b.order) + 1
+	image := &epubImage{
+		id:   fmt.Sprintf("img-%d", n),
+		href: fmt.Sprintf("images/img-%d%s", n, mime.Extension()),
+		mime: mime.String(),
+		file: file,
+	}
+	b.images[file] = image
+	b.order = append(b.order, image)
+	return image.href, nil
+}
+
+func (b *epubBook) writeXHTML(title string, body string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="%s" xml:lang="%s">
+<head>
+<meta charset="UTF-8"/>
+<title>%s</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+%s
+</body>
+</html>
+`, b.language, b.language, html.EscapeString(title), body)
+}
+
+func (b *epubBook) getNav() string {
+	var nav strings.Builder
+	nav.WriteString(`<nav epub:type="toc" id="toc">`)
+	nav.WriteString(b.content.getTOC())
+	nav.WriteString(`</nav>`)
+	return b.writeXHTML(b.settings.Title, nav.String())
+}
+
+func writeNavPoints(entries []*tocEntry, order *int, output *strings.Builder) {
+	for _, entry := range entries {
+		*order++
+		output.WriteString(fmt.Sprintf(`<navPoint id="nav-%d" playOrder="%d"><navLabel><text>%s</text></navLabel>`+
+			`<content src="%s"/>`, *order, *order, html.EscapeString(entry.title), html.EscapeString(entry.href)))
+		writeNavPoints(entry.children, order, output)
+		output.WriteString("</navPoint>\n")
+	}
+}
+
+// getNCX returns the navigation for readers that support only EPUB 2
+func (b *epubBook) getNCX() string {
+	var ncx strings.Builder
+	ncx.WriteString(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+<head><meta name="dtb:uid" content="%s"/></head>
+<docTitle><text>%s</text></docTitle>
+<navMap>
+`, b.id, html.EscapeString(b.settings.Title)))
+	order := 0
+	writeNavPoints(b.content.toc, &order, &ncx)
+	ncx.WriteString("</navMap>\n</ncx>\n")
+	return ncx.String()
+}
+
+func (b *epubBook) getPackage() string {
+	var opf strings.Builder
+	opf.WriteString(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="%s">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:identifier id="book-id">%s</dc:identifier>
+<dc:title>%s</dc:title>
+<dc:language>%s</dc:language>
+`, b.language, b.id, html.EscapeString(b.settings.Title), b.language))
+	for _, author := range strings.Split(b.settings.Authors, ",") {
+		if author = strings.TrimSpace(author); author != "" {
+			opf.WriteString(fmt.Sprintf("<dc:creator>%s</dc:creator>\n", html.EscapeString(author)))
+		}
+	}
+	if b.settings.Subtitle != "" {
+		opf.WriteString(fmt.Sprintf("<dc:description>%s</dc:description>\n", html.EscapeString(b.settings.Subtitle)))
+	}
+	opf.WriteString(fmt.Sprintf(`<meta property="dcterms:modified">%s</meta>
+</metadata>
+<manifest>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+<item id="style" href="style.css" media-type="text/css"/>
+<item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>
+`, time.Now().UTC().Format("2006-01-02T15:04:05Z")))
+	for i := range b.chapters {
+		opf.WriteString(fmt.Sprintf(`<item id="chapter-%d" href="chapter-%d.xhtml" media-type="application/xhtml+xml"/>`+"\n",
+			i+1, i+1))
+	}
+	if len(b.content.terms) > 0 {
+		opf.WriteString(`<item id="index" href="index.xhtml" media-type="application/xhtml+xml"/>` + "\n")
+	}
+	for _, image := range b.order {
+		opf.WriteString(fmt.Sprintf(`<item id="%s" href="%s" media-type="%s"/>`+"\n", image.id, image.href, image.mime))
+	}
+	opf.WriteString("</manifest>\n<spine toc=\"ncx\">\n<itemref idref=\"cover\"/>\n<itemref idref=\"nav\"/>\n")
+	for i := range b.chapters {
+		opf.WriteString(fmt.Sprintf("<itemref idref=\"chapter-%d\"/>\n", i+1))
+	}
+	if len(b.content.terms) > 0 {
+		opf.WriteString("<itemref idref=\"index\"/>\n")
+	}
+	opf.WriteString("</spine>\n</package>\n")
+	return opf.String()
+}
+
+func (b *epubBook) getCover() string {
+	var cover strings.Builder
+	cover.WriteString(fmt.Sprintf(`<section class="cover" epub:type="cover"><h1>%s</h1>`,
+		html.EscapeString(b.settings.Title)))
+	if b.settings.Subtitle != "" {
+		cover.WriteString(fmt.Sprintf(`<h2>%s</h2>`, html.EscapeString(b.settings.Subtitle)))
+	}
+	if b.settings.Authors != "" {
+		cover.WriteString(fmt.Sprintf(`<p class="authors">%s</p>`, html.EscapeString(b.settings.Authors)))
+	}
+	cover.WriteString(`</section>`)
+	return b.writeXHTML(b.settings.Title, cover.String())
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// CreateEPUB writes the markdown files in a folder of the library as an EPUB 3 book. Chapters and
+// their h1 and h2 headings form the navigation; images linked with ~library/ are included in the book.
+// Tagged terms are listed in an index page at the end.
+func CreateEPUB(project *core.Project, loc string, settings BookSettings, w io.Writer) error {
+	if settings.Title == "" {
+		settings.Title = filepath.Base(loc)
+	}
+	b := &epubBook{
+		settings: settings,
+		id:       "urn:uuid:" + uuid.NewSHA1(uuid.NameSpaceURL, []byte(project.Config.UUID+loc)).String(),
+		language: "en",
+		images:   make(map[string]*epubImage),
+	}
+	if languages := project.Config.Public.Languages; len(languages) > 0 {
+		b.language = languages[0]
+	}
+
+	chapters, err := getChapters(project, loc, b.addImage)
+	if err != nil {
+		return err
+	}
+	b.content, err = prepareBook(chapters, settings, func(idx int) string {
+		return fmt.Sprintf("chapter-%d.xhtml", idx+1)
+	})
+	if err != nil {
+		return err
+	}
+	for _, chapter := range b.content.chapters {
+		b.chapters = append(b.chapters, b.writeXHTML(chapter.title, fmt.Sprintf(
+			`<section epub:type="chapter" id="%s"><div class="sectionTitle">%s</div>%s</section>`,
+			chapter.id, html.EscapeString(chapter.label), chapter.body)))
+	}
+
+	var css bytes.Buffer
+	addStyle("common", &css)
+	for _, style := range settings.Styles {
+		addStyle(style, &css)
+	}
+
+	zw := zip.NewWriter(w)
+	// the mimetype must be the first entry and must not be compressed
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := mw.Write([]byte(EPUBMime)); err != nil {
+		return err
+	}
+
+	files := []struct {
+		name string
+		data string
+	}{
+		{"META-INF/container.xml", epubContainer},
+		{"OEBPS/content.opf", b.getPackage()},
+		{"OEBPS/nav.xhtml", b.getNav()},
+		{"OEBPS/toc.ncx", b.getNCX()},
+		{"OEBPS/style.css", css.String()},
+		{"OEBPS/cover.xhtml", b.getCover()},
+	}
+	for i, chapter := range b.chapters {
+		files = append(files, struct {
+			name string
+			data string
+		}{fmt.Sprintf("OEBPS/chapter-%d.xhtml", i+1), chapter})
+	}
+	if len(b.content.terms) > 0 {
+		files = append(files, struct {
+			name string
+			data string
+		}{"OEBPS/index.xhtml", b.writeXHTML("Index", `<section epub:type="index">`+b.content.getIndex()+`</section>`)})
+	}
+	for _, file := range files {
+		if err := writeZipFile(zw, file.name, []byte(file.data)); err != nil {
+			return err
+		}
+	}
+
+	for _, image := range b.order {
+		data, err := ioutil.ReadFile(image.file)
+		if err != nil {
+			return err
+		}
+		if err := writeZipFile(zw, "OEBPS/"+image.href, data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,58 @@
+package library
+
+import (
+	"almost-scrum/core"
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateEPUB(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "ash-epub")
+	defer os.RemoveAll(dir)
+	project := &core.Project{Path: dir}
+	folder := filepath.Join(dir, core.ProjectLibraryFolder, "Guide")
+	_ = os.MkdirAll(folder, 0755)
+	_ = ioutil.WriteFile(filepath.Join(folder, "1. Intro.md"),
+		[]byte("# Welcome\n\n\"Quoted\" text & more<br>\n\n## Install\n\n![logo](~library/Guide/logo.png#size=50)\n"), 0644)
+	_ = ioutil.WriteFile(filepath.Join(folder, "2. Usage.md"), []byte("# Usage\n\nRun it.\n"), 0644)
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x02\x00\x00\x00")
+	_ = ioutil.WriteFile(filepath.Join(folder, "logo.png"), png, 0644)
+
+	var book bytes.Buffer
+	assert.Nil(t, CreateEPUB(project, "/Guide", BookSettings{Title: "Guide", Authors: "Ann, Bob"}, &book))
+
+	r, err := zip.NewReader(bytes.NewReader(book.Bytes()), int64(book.Len()))
+	assert.Nil(t, err)
+	assert.Equal(t, "mimetype", r.File[0].Name)
+	assert.Equal(t, zip.Store, r.File[0].Method)
+
+	files := make(map[string]string)
+	for _, file := range r.File {
+		f, _ := file.Open()
+		data, _ := ioutil.ReadAll(f)
+		files[file.Name] = string(data)
+		if filepath.Ext(file.Name) == ".xhtml" || filepath.Ext(file.Name) == ".opf" {
+			decoder := xml.NewDecoder(bytes.NewReader(data))
+			for {
+				if _, err := decoder.Token(); err != nil {
+					assert.Equal(t, io.EOF, err, file.Name)
+					break
+				}
+			}
+		}
+	}
+	assert.Contains(t, files, "OEBPS/images/img-1.png")
+	assert.Contains(t, files["OEBPS/chapter-1.xhtml"], `src="images/img-1.png"`)
+	assert.Contains(t, files["OEBPS/nav.xhtml"], `<a href="chapter-1.xhtml#section-0">Intro</a><ol><li>`+
+		`<a href="chapter-1.xhtml#section-0-welcome">Welcome</a><ol><li><a href="chapter-1.xhtml#section-0-install">`+
+		`Install</a></li></ol></li></ol>`)
+	assert.Contains(t, files["OEBPS/content.opf"], "<dc:creator>Bob</dc:creator>")
+}
@@ -2,7 +2,9 @@ package library
 
 import (
 	"almost-scrum/core"
+	"github.com/code-to-go/fed"
 	"github.com/code-to-go/fed/extfs"
+	"github.com/code-to-go/fed/transport"
 	"io"
 	"io/ioutil"
 	"os"
@@ -46,7 +48,7 @@ func getItem(path string, fileInfo os.FileInfo) Item {
 	}
 
 	var attr core.FileAttr
-	extfs.Get(path, attr)
+	extfs.Get(path, &attr)
 	return Item{
 		Name:     fileInfo.Name(),
 		Size:     size,
@@ -97,17 +99,48 @@ func List(project *core.Project, path string) ([]Item, error) {
 	return items, nil
 }
 
-func MoveFile(project *core.Project, oldPath string, path string) error {
-	var err error
-	path = filepath.Join(project.Path, core.ProjectLibraryFolder, path)
-	if oldPath, err = AbsPath(project, oldPath); err != nil {
+// moveAttrs moves the extended attributes of a file or of the files in a folder that has been moved
+// from source to dest. The attributes are moved one by one, since extfs.Move does not create the
+// folders of the destination. When tombstone is true, the FedAttr is also left on the source, since
+// the federation shares the deletion of a path only when its FedAttr is still there.
+func moveAttrs(source string, dest string, tombstone bool) {
+	for _, p := range walkItem(dest) {
+		rel, err := filepath.Rel(dest, p)
+		if err != nil {
+			continue
+		}
+		src := filepath.Join(source, rel)
+		for _, attr := range []interface{}{&core.FileAttr{}, &fed.FedAttr{}, &transport.FileAttr{}} {
+			if extfs.Get(src, attr) {
+				core.IsErr(extfs.Set(p, attr, true), "cannot move attributes of %s to %s", src, p)
+				if _, ok := attr.(*fed.FedAttr); ok && tombstone {
+					continue
+				}
+				_ = extfs.Delete(src, attr)
+			}
+		}
+	}
+}
+
+// MoveFile moves or renames a file or a folder in the library. It fails with ErrLocked when a
+// file is checked out by another user.
+func MoveFile(project *core.Project, oldPath string, path string, user string) error {
+	oldAbsPath, err := AbsPath(project, oldPath)
+	if err != nil {
 		return err
 	}
-	err = os.Rename(oldPath, path)
+	if err := checkLock(oldAbsPath, user); err != nil {
+		return err
+	}
+	absPath := filepath.Join(project.Path, core.ProjectLibraryFolder, path)
+	// the documents in a folder are listed before the rename, since they are no longer there after
+	queueIndexItem(project, oldPath, oldAbsPath)
+	err = os.Rename(oldAbsPath, absPath)
 	if err != nil {
 		return err
 	}
-	extfs.Move(oldPath, path)
+	moveAttrs(oldAbsPath, absPath, true)
+	queueIndexItem(project, path, absPath)
 	return nil
 }
 
@@ -119,18 +152,6 @@ func CreateFolder(project *core.Project, path string, owner string) error {
 	return extfs.Set(path, &core.FileAttr{Owner: owner}, true)
 }
 
-func DeleteFile(project *core.Project, path string) error {
-	path = filepath.Join(project.Path, core.ProjectLibraryFolder, path)
-	err := os.Remove(path)
-	if err != nil {
-		return err
-	}
-	//attr, _ := fs.GetExtendedAttr(path)
-	//attr.Modified = time.Now()
-	//_ = fs.SetExtendedAttr(path, attr)
-	return nil
-}
-
 // AbsPath returns the absolute path for a resource stored in the library.
 func AbsPath(project *core.Project, path string) (string, error) {
 	p := filepath.Join(project.Path, core.ProjectLibraryFolder, path)
@@ -142,19 +163,29 @@ func ArchivePath(project *core.Project, path string) (string, error) {
 	return filepath.Abs(p)
 }
 
+// isInside returns true when the absolute path is the root or a file under the root
+func isInside(root string, absPath string) bool {
+	rel, err := filepath.Rel(root, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// SetFileInLibrary writes the content of the reader in the library. Private files share identical content
+// in the blob store; public files keep their own copy, since the federation changes them in place. It
+// fails with ErrLocked when the file is checked out by a user other than the owner.
 func SetFileInLibrary(project *core.Project, path string, reader io.ReadCloser,
 	owner string, public bool) (string, error) {
 	var err error
+	defer queueIndex(project, path)
 	path = filepath.Join(project.Path, core.ProjectLibraryFolder, path)
-
-	writer, err := os.Create(path)
-	if err != nil {
-		logrus.Warnf("Cannot open file %s for writing: %v", path, err)
+	if err = checkLock(path, owner); err != nil {
 		return "", err
 	}
-	defer writer.Close()
 
-	_, err = io.Copy(writer, reader)
+	if public {
+		err = core.WriteFile(path, reader)
+	} else {
+		err = core.StoreFile(project, path, reader)
+	}
 	if err != nil {
 		logrus.Warnf("cannot write file %s in library: %v", path, err)
 		return "", err
@@ -168,6 +199,7 @@ func SetFileInLibrary(project *core.Project, path string, reader io.ReadCloser,
 	if err := extfs.Set(path, core.FileAttr{
 		Owner:  owner,
 		Public: public,
+		Lock:   getLock(path),
 	}, true); err != nil {
 		logrus.Warnf("Cannot set owner for file %s: %v", path, owner)
 	}
@@ -187,6 +219,10 @@ func SetVisibility(project *core.Project, path string, public bool) error {
 	}
 
 	err = project.Fed.SetTracked(fullPath, public)
+	if files == nil && public {
+		// the federation changes public files in place, so they cannot share the content
+		core.IsErr(core.DetachFile(fullPath), "cannot detach %s from blob store", fullPath)
+	}
 	logrus.Infof("set visibility of file %s tp %t", path, public)
 	return err
 }
@@ -2,8 +2,14 @@ package library
 
 import (
 	"almost-scrum/core"
+	"almost-scrum/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/code-to-go/fed/extfs"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -51,3 +57,30 @@ func TestExport(t *testing.T) {
 
 	print(html)
 }
+
+func TestVersionsShareBlobs(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "ash-blobs")
+	defer os.RemoveAll(dir)
+	extfs.Init(filepath.Join(dir, "extfs"))
+
+	project := &core.Project{Path: filepath.Join(dir, "project"), Fed: davFed{}}
+	for _, folder := range []string{core.ProjectLibraryFolder, core.ProjectArchiveFolder} {
+		_ = os.MkdirAll(filepath.Join(project.Path, folder), 0755)
+	}
+	_, err := SetFileInLibrary(project, "/Spec~0.1.md", ioutil.NopCloser(strings.NewReader("spec")), "alice", false)
+	assert.Nil(t, err)
+	path, err := IncreaseVersion(project, "/Spec~0.1.md", "alice", false)
+	assert.Nil(t, err)
+
+	// the new version, the archived version and the blob are the same file
+	next := filepath.Join(project.Path, core.ProjectLibraryFolder, path)
+	links, _ := fs.GetLinks(next)
+	assert.Equal(t, uint64(3), links)
+
+	// public files are changed in place by the federation, so they get their own copy
+	assert.Nil(t, SetVisibility(project, path, true))
+	links, _ = fs.GetLinks(next)
+	assert.Equal(t, uint64(1), links)
+	data, _ := ioutil.ReadFile(filepath.Join(project.Path, core.ProjectArchiveFolder, "Spec~0.1.md"))
+	assert.Equal(t, "spec", string(data))
+}
@@ -0,0 +1,177 @@
+package library
+
+import (
+	"almost-scrum/core"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/code-to-go/fed/extfs"
+	"github.com/code-to-go/fed/transport"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultLockDuration is the validity of a check-out when the user does not ask for a different one
+const DefaultLockDuration = 24 * time.Hour
+
+// ErrLocked is returned when a document is checked out by another user
+var ErrLocked = errors.New("document is checked out by another user")
+
+// ErrNotLocked is returned when a document to check in or unlock is not checked out
+var ErrNotLocked = errors.New("document is not checked out")
+
+// getLock returns the lock of a file in the library, or nil when the file is not checked out or the
+// check-out has expired
+func getLock(absPath string) *core.FileLock {
+	var attr core.FileAttr
+	if !extfs.Get(absPath, &attr) || attr.Lock == nil || time.Now().After(attr.Lock.Expires) {
+		return nil
+	}
+	return attr.Lock
+}
+
+// checkLock returns ErrLocked when a file or any file in a folder is checked out by a user other than
+// the given one
+func checkLock(absPath string, user string) error {
+	for _, p := range walkItem(absPath) {
+		if lock := getLock(p); lock != nil && lock.User != user {
+			return ErrLocked
+		}
+	}
+	return nil
+}
+
+// setLock changes the lock of a file while keeping the owner and the visibility. The federation
+// exports a file only when its content changes, so the information about the last push is reset to
+// share the new lock with the other peers.
+func setLock(absPath string, lock *core.FileLock) error {
+	var attr core.FileAttr
+	extfs.Get(absPath, &attr)
+	attr.Lock = lock
+	if err := extfs.Set(absPath, &attr, true); err != nil {
+		return err
+	}
+
+	resetPush(absPath)
+	return nil
+}
+
+// resetPush clears the information about the last push of a file, so that the federation exports
+// the file again even when its content has not changed.
+func resetPush(absPath string) {
+	var pushAttr transport.FileAttr
+	if extfs.Get(absPath, &pushAttr) {
+		pushAttr.ModTime = time.Time{}
+		pushAttr.PushHash = nil
+		core.IsErr(extfs.Set(absPath, &pushAttr, false), "cannot reset push info of %s", absPath)
+	}
+}
+
+// GetLock returns the active lock of a document in the library, or nil when the document is not
+// checked out
+func GetLock(project *core.Project, path string) (*core.FileLock, error) {
+	absPath, err := AbsPath(project, path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		return nil, err
+	}
+	return getLock(absPath), nil
+}
+
+// CheckOut locks a document for the user for the given duration. Until the lock expires or is
+// released, other users cannot change the document. A user can check out again a document to
+// extend the lock.
+func CheckOut(project *core.Project, path string, user string, duration time.Duration) (*core.FileLock, error) {
+	absPath, err := AbsPath(project, path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, core.ErrInvalidType
+	}
+	if err := checkLock(absPath, user); err != nil {
+		return nil, err
+	}
+	if duration <= 0 {
+		duration = DefaultLockDuration
+	}
+
+	now := time.Now()
+	lock := &core.FileLock{User: user, Since: now, Expires: now.Add(duration)}
+	if err := setLock(absPath, lock); err != nil {
+		return nil, err
+	}
+	logrus.Infof("%s checked out by %s until %s", path, user, lock.Expires)
+	return lock, nil
+}
+
+// CheckIn releases the lock of the user and creates the next version of the document. When reader
+// is not nil, its content replaces the content of the new version. It returns the path of the new
+// version.
+func CheckIn(project *core.Project, path string, user string, reader io.ReadCloser, public bool) (string, error) {
+	absPath, err := AbsPath(project, path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		return "", err
+	}
+	lock := getLock(absPath)
+	if lock == nil {
+		return "", ErrNotLocked
+	}
+	if lock.User != user {
+		return "", ErrLocked
+	}
+
+	if err := setLock(absPath, nil); err != nil {
+		return "", err
+	}
+	path_, err := IncreaseVersion(project, path, user, public)
+	if err != nil {
+		return "", err
+	}
+	if reader != nil {
+		if _, err := SetFileInLibrary(project, path_, reader, user, public); err != nil {
+			return "", err
+		}
+	}
+	logrus.Infof("%s checked in by %s as %s", path, user, path_)
+	return path_, nil
+}
+
+// Unlock releases the lock on a document without creating a new version. Only the user holding
+// the lock and the admins can unlock a document.
+func Unlock(project *core.Project, path string, user string) error {
+	absPath, err := AbsPath(project, path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		return err
+	}
+	lock := getLock(absPath)
+	if lock == nil {
+		return ErrNotLocked
+	}
+	if lock.User != user && !core.IsAdmin(project, user) {
+		return ErrLocked
+	}
+
+	if err := setLock(absPath, nil); err != nil {
+		return err
+	}
+	if lock.User != user {
+		logrus.Warnf("lock of %s on %s broken by %s", lock.User, path, user)
+	} else {
+		logrus.Infof("%s unlocked by %s", path, user)
+	}
+	return nil
+}
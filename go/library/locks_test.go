@@ -0,0 +1,62 @@
+package library
+
+import (
+	"almost-scrum/core"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-to-go/fed/extfs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocks(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "ash-locks")
+	defer os.RemoveAll(dir)
+	extfs.Init(filepath.Join(dir, "extfs"))
+
+	project := &core.Project{Path: filepath.Join(dir, "project")}
+	project.Config.Public.Admins = []string{"carol"}
+	spec := filepath.Join(project.Path, core.ProjectLibraryFolder, "Specs", "Spec~0.3.docx")
+	_ = os.MkdirAll(filepath.Dir(spec), 0755)
+	_ = ioutil.WriteFile(spec, []byte("spec"), 0644)
+	_ = extfs.Set(spec, core.FileAttr{Owner: "alice", Public: true}, true)
+
+	lock, err := CheckOut(project, "/Specs/Spec~0.3.docx", "alice", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", lock.User)
+	assert.WithinDuration(t, time.Now().Add(DefaultLockDuration), lock.Expires, time.Minute)
+
+	var attr core.FileAttr
+	assert.True(t, extfs.Get(spec, &attr))
+	assert.Equal(t, "alice", attr.Owner)
+	assert.True(t, attr.Public)
+
+	_, err = CheckOut(project, "/Specs/Spec~0.3.docx", "bob", time.Hour)
+	assert.Equal(t, ErrLocked, err)
+	_, err = SetFileInLibrary(project, "/Specs/Spec~0.3.docx", ioutil.NopCloser(strings.NewReader("x")), "bob", false)
+	assert.Equal(t, ErrLocked, err)
+	assert.Equal(t, ErrLocked, DeleteFile(project, "/Specs", "bob"))
+	assert.Equal(t, ErrLocked, MoveFile(project, "/Specs/Spec~0.3.docx", "/Spec~0.3.docx", "bob"))
+	_, err = CheckIn(project, "/Specs/Spec~0.3.docx", "bob", nil, false)
+	assert.Equal(t, ErrLocked, err)
+	assert.Equal(t, ErrLocked, Unlock(project, "/Specs/Spec~0.3.docx", "bob"))
+
+	assert.Nil(t, Unlock(project, "/Specs/Spec~0.3.docx", "carol"))
+	lock, err = GetLock(project, "/Specs/Spec~0.3.docx")
+	assert.Nil(t, err)
+	assert.Nil(t, lock)
+	assert.Equal(t, ErrNotLocked, Unlock(project, "/Specs/Spec~0.3.docx", "alice"))
+
+	_, err = CheckOut(project, "/Specs/Spec~0.3.docx", "bob", -time.Hour)
+	assert.Nil(t, err)
+	_ = extfs.Set(spec, core.FileAttr{Owner: "alice", Lock: &core.FileLock{User: "bob",
+		Expires: time.Now().Add(-time.Minute)}}, true)
+	lock, _ = GetLock(project, "/Specs/Spec~0.3.docx")
+	assert.Nil(t, lock)
+	_, err = CheckOut(project, "/Specs", "alice", time.Hour)
+	assert.Equal(t, core.ErrInvalidType, err)
+}
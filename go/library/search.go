@@ -0,0 +1,197 @@
+package library
+
+import (
+	"almost-scrum/core"
+	"math"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// Snippets settings: number of snippets for each document and characters around the match
+const (
+	MaxSnippets    = 3
+	SnippetContext = 80
+)
+
+// Hit is a library document found by Search. Path is relative to the library.
+type Hit struct {
+	Path     string    `json:"path"`
+	Folder   string    `json:"folder"`
+	Name     string    `json:"name"`
+	ModTime  time.Time `json:"modTime"`
+	Score    float64   `json:"score"`
+	Snippets []string  `json:"snippets"`
+}
+
+type extractedText struct {
+	modTime time.Time
+	text    string
+}
+
+var (
+	textCache   = cache.New(10*time.Minute, 20*time.Minute)
+	wordMatch   = regexp.MustCompile(`[#@]?[\pL\p{Mc}\p{Mn}][\pL\p{Mc}\p{Mn}\p{N}_']*`)
+	spacesMatch = regexp.MustCompile(`\s+`)
+)
+
+// isIndexed returns true when the file is a document whose text is indexed
+func isIndexed(p string) bool {
+	return !strings.HasPrefix(filepath.Base(p), ".") &&
+		core.HasStringInSlice(core.IndexedDocumentExts, strings.ToLower(filepath.Ext(p)))
+}
+
+// queueIndex updates the index for a document in the library after it is created, changed or removed
+func queueIndex(project *core.Project, p string) {
+	if isIndexed(p) {
+		core.QueueReIndexSource(project, filepath.Join(core.ProjectLibraryFolder, p))
+	}
+}
+
+// getText returns the text of a document. Texts are kept in a cache until the file changes.
+func getText(absPath string, modTime time.Time) (string, error) {
+	if t, found := textCache.Get(absPath); found && t.(extractedText).modTime.Equal(modTime) {
+		return t.(extractedText).text, nil
+	}
+	data, err := core.ExtractText(absPath)
+	if err != nil {
+		return "", err
+	}
+	text := string(data)
+	textCache.Set(absPath, extractedText{modTime: modTime, text: text}, cache.DefaultExpiration)
+	return text, nil
+}
+
+// getSnippet returns the text around a match, cut at word boundaries
+func getSnippet(text string, start, end int) string {
+	from, to := start-SnippetContext, end+SnippetContext
+	prefix, suffix := "…", "…"
+	if from <= 0 {
+		from, prefix = 0, ""
+	} else {
+		for from < start && !utf8.RuneStart(text[from]) {
+			from++
+		}
+		if idx := strings.IndexAny(text[from:start], " \t\n"); idx >= 0 {
+			from += idx + 1
+		}
+	}
+	if to >= len(text) {
+		to, suffix = len(text), ""
+	} else {
+		for to > end && !utf8.RuneStart(text[to]) {
+			to--
+		}
+		if idx := strings.LastIndexAny(text[end:to], " \t\n"); idx >= 0 {
+			to = end + idx
+		}
+	}
+	return prefix + strings.TrimSpace(spacesMatch.ReplaceAllString(text[from:to], " ")) + suffix
+}
+
+// scoreText counts the words in the text that match each key and returns the score of the text and
+// the snippets around the first matches. Score grows with the number of matching keys and,
+// logarithmically, with their frequency.
+func scoreText(analyzer *core.Analyzer, text string, keys []string) (float64, []string) {
+	queryKeys := make(map[string]int)
+	for i, key := range keys {
+		for _, k := range analyzer.Keys(key) {
+			queryKeys[k] = i
+		}
+	}
+
+	counts := make([]int, len(keys))
+	wordKeys := make(map[string][]string)
+	var snippets []string
+	lastEnd := -1
+	for _, loc := range wordMatch.FindAllStringIndex(text, -1) {
+		word := strings.ToLower(text[loc[0]:loc[1]])
+		ks, found := wordKeys[word]
+		if !found {
+			ks = analyzer.Keys(word)
+			wordKeys[word] = ks
+		}
+		for _, k := range ks {
+			if i, found := queryKeys[k]; found {
+				counts[i]++
+				if len(snippets) < MaxSnippets && loc[0] > lastEnd {
+					snippets = append(snippets, getSnippet(text, loc[0], loc[1]))
+					lastEnd = loc[1] + 2*SnippetContext
+				}
+				break
+			}
+		}
+	}
+
+	score, matched := 0.0, 0
+	for _, cnt := range counts {
+		if cnt > 0 {
+			score += 1 + math.Log(float64(cnt))
+			matched++
+		}
+	}
+	return score * float64(matched) / float64(len(keys)), snippets
+}
+
+// Search looks for documents in the library that contain the words in the text. When folder is not
+// empty, only documents in the folder and its subfolders are returned. Results are sorted by score.
+func Search(project *core.Project, text string, folder string) ([]Hit, error) {
+	hits := make([]Hit, 0)
+	keys := strings.Fields(text)
+	if len(keys) == 0 {
+		return hits, nil
+	}
+	folder = strings.Trim(filepath.ToSlash(folder), "/")
+
+	results, err := core.SearchDocuments(project, false, keys...)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		p := "/" + strings.TrimPrefix(result.Link, core.ProjectLibraryFolder+"/")
+		if folder != "" && !strings.HasPrefix(p, "/"+folder+"/") {
+			continue
+		}
+		absPath, err := AbsPath(project, p)
+		if err != nil {
+			continue
+		}
+		text, err := getText(absPath, result.ModTime)
+		if core.IsErr(err, "cannot extract text from %s", p) {
+			continue
+		}
+
+		score, snippets := scoreText(project.Analyzer, text, keys)
+		name := strings.ToLower(path.Base(p))
+		for _, key := range keys {
+			if strings.Contains(name, strings.ToLower(key)) {
+				score++
+			}
+		}
+		if snippets == nil {
+			snippets = []string{}
+		}
+		hits = append(hits, Hit{
+			Path:     p,
+			Folder:   path.Dir(p),
+			Name:     path.Base(p),
+			ModTime:  result.ModTime,
+			Score:    math.Round(score*100) / 100,
+			Snippets: snippets,
+		})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ModTime.After(hits[j].ModTime)
+	})
+	return hits, nil
+}
@@ -0,0 +1,22 @@
+package library
+
+import (
+	"almost-scrum/core"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoreText(t *testing.T) {
+	analyzer := core.NewAnalyzer([]string{"en"}, nil)
+	text := "The client refreshes the token before it expires. A token refresh is required " +
+		"for every session and the token is stored in memory."
+
+	score, snippets := scoreText(analyzer, text, []string{"token", "refresh"})
+	assert.Greater(t, score, 2.0)
+	assert.Len(t, snippets, 1)
+	assert.Contains(t, snippets[0], "refreshes the token")
+
+	other, _ := scoreText(analyzer, text, []string{"token", "database"})
+	assert.Less(t, other, score)
+}
@@ -0,0 +1,232 @@
+package library
+
+import (
+	"almost-scrum/core"
+	"almost-scrum/fs"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/code-to-go/fed"
+	"github.com/code-to-go/fed/extfs"
+	"github.com/code-to-go/fed/transport"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultTrashRetention is the number of days items stay in the trash when the project does not
+// define a retention
+const DefaultTrashRetention = 30
+
+// TrashItem is a file or a folder deleted from the library. The item is stored in the trash folder
+// under its ID together with a JSON file with the information below.
+type TrashItem struct {
+	ID      string    `json:"id"`
+	Path    string    `json:"path"`
+	Name    string    `json:"name"`
+	Dir     bool      `json:"dir"`
+	Size    int64     `json:"size"`
+	Owner   string    `json:"owner"`
+	Deleted time.Time `json:"deleted"`
+	User    string    `json:"user"`
+}
+
+var trashID = regexp.MustCompile(`^[0-9a-f]+$`)
+
+func getTrashPath(project *core.Project, id string) (string, error) {
+	if !trashID.MatchString(id) {
+		return "", core.ErrNoFound
+	}
+	return filepath.Join(project.Path, core.ProjectTrashFolder, id), nil
+}
+
+// walkItem returns the files and folders in a path, with the content of folders before the folders
+func walkItem(p string) []string {
+	var paths []string
+	_ = filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+		if err == nil {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	return paths
+}
+
+// queueIndexItem updates the index for a file or all the files in a folder of the library
+func queueIndexItem(project *core.Project, path string, absPath string) {
+	for _, p := range walkItem(absPath) {
+		rel, err := filepath.Rel(absPath, p)
+		if err == nil {
+			queueIndex(project, filepath.Join(path, rel))
+		}
+	}
+}
+
+// DeleteFile moves a file or a folder from the library to the trash. The extended attributes, i.e. the
+// owner and the federation tracking, are moved with the item so that Restore brings them back. It fails
+// with ErrLocked when a file is checked out by another user.
+func DeleteFile(project *core.Project, path string, user string) error {
+	absPath := filepath.Join(project.Path, core.ProjectLibraryFolder, path)
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return err
+	}
+	if err := checkLock(absPath, user); err != nil {
+		return err
+	}
+	var attr core.FileAttr
+	extfs.Get(absPath, &attr)
+
+	item := TrashItem{
+		ID:      fmt.Sprintf("%x", time.Now().UnixNano()/1000),
+		Path:    filepath.ToSlash(filepath.Clean("/" + path)),
+		Name:    info.Name(),
+		Dir:     info.IsDir(),
+		Size:    info.Size(),
+		Owner:   attr.Owner,
+		Deleted: time.Now(),
+		User:    user,
+	}
+	trashPath, _ := getTrashPath(project, item.ID)
+	if err := os.MkdirAll(trashPath, 0755); err != nil {
+		return err
+	}
+	queueIndexItem(project, path, absPath)
+
+	dest := filepath.Join(trashPath, item.Name)
+	if err := os.Rename(absPath, dest); err != nil {
+		_ = os.Remove(trashPath)
+		return err
+	}
+	moveAttrs(absPath, dest, true)
+	if err := fs.WriteJSON(trashPath+".json", &item); err != nil {
+		return err
+	}
+	logrus.Infof("Moved %s to trash %s", path, item.ID)
+
+	PurgeExpired(project)
+	return nil
+}
+
+// ListTrash returns the items in the trash, the most recently deleted first. Expired items are
+// purged before listing.
+func ListTrash(project *core.Project) ([]TrashItem, error) {
+	PurgeExpired(project)
+
+	items := make([]TrashItem, 0)
+	infos, err := ioutil.ReadDir(filepath.Join(project.Path, core.ProjectTrashFolder))
+	if os.IsNotExist(err) {
+		return items, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if filepath.Ext(info.Name()) != ".json" {
+			continue
+		}
+		var item TrashItem
+		p := filepath.Join(project.Path, core.ProjectTrashFolder, info.Name())
+		if err := fs.ReadJSON(p, &item); core.IsErr(err, "invalid trash item %s", p) {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Deleted.After(items[j].Deleted)
+	})
+	return items, nil
+}
+
+func getTrashItem(project *core.Project, id string) (TrashItem, string, error) {
+	var item TrashItem
+	trashPath, err := getTrashPath(project, id)
+	if err != nil {
+		return item, "", err
+	}
+	if err := fs.ReadJSON(trashPath+".json", &item); err != nil {
+		if os.IsNotExist(err) {
+			err = core.ErrNoFound
+		}
+		return item, "", err
+	}
+	return item, trashPath, nil
+}
+
+// Restore moves an item from the trash back to its original path in the library. It fails with
+// ErrExists when another item has been created in the same path in the meantime. The information
+// about the last push is reset, so that the peers that dropped the item receive it again.
+func Restore(project *core.Project, id string) (TrashItem, error) {
+	item, trashPath, err := getTrashItem(project, id)
+	if err != nil {
+		return item, err
+	}
+
+	absPath := filepath.Join(project.Path, core.ProjectLibraryFolder, item.Path)
+	if _, err := os.Stat(absPath); err == nil {
+		return item, core.ErrExists
+	}
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return item, err
+	}
+
+	source := filepath.Join(trashPath, item.Name)
+	if err := os.Rename(source, absPath); err != nil {
+		return item, err
+	}
+	moveAttrs(source, absPath, false)
+	for _, p := range walkItem(absPath) {
+		resetPush(p)
+	}
+	queueIndexItem(project, item.Path, absPath)
+
+	_ = os.Remove(trashPath)
+	_ = os.Remove(trashPath + ".json")
+	logrus.Infof("Restored %s from trash %s", item.Path, id)
+	return item, nil
+}
+
+// Purge removes permanently an item from the trash with its extended attributes
+func Purge(project *core.Project, id string) error {
+	item, trashPath, err := getTrashItem(project, id)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range walkItem(filepath.Join(trashPath, item.Name)) {
+		_ = extfs.Delete(p, &core.FileAttr{})
+		_ = extfs.Delete(p, &fed.FedAttr{})
+		_ = extfs.Delete(p, &transport.FileAttr{})
+	}
+	if err := os.RemoveAll(trashPath); err != nil {
+		return err
+	}
+	logrus.Infof("Purged %s from trash %s", item.Path, id)
+	return os.Remove(trashPath + ".json")
+}
+
+// PurgeExpired removes the items that have been in the trash longer than the retention of the project
+func PurgeExpired(project *core.Project) {
+	retention := project.Config.Public.TrashRetention
+	if retention <= 0 {
+		retention = DefaultTrashRetention
+	}
+	limit := time.Now().AddDate(0, 0, -retention)
+
+	infos, _ := ioutil.ReadDir(filepath.Join(project.Path, core.ProjectTrashFolder))
+	for _, info := range infos {
+		if filepath.Ext(info.Name()) != ".json" || info.ModTime().After(limit) {
+			continue
+		}
+		id := strings.TrimSuffix(info.Name(), ".json")
+		item, _, err := getTrashItem(project, id)
+		if err == nil && item.Deleted.Before(limit) {
+			core.IsErr(Purge(project, id), "cannot purge trash item %s", id)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package library
+
+import (
+	"almost-scrum/core"
+	"almost-scrum/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/code-to-go/fed"
+	"github.com/code-to-go/fed/extfs"
+	"github.com/code-to-go/fed/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrash(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "ash-trash")
+	defer os.RemoveAll(dir)
+	extfs.Init(filepath.Join(dir, "extfs"))
+
+	project := &core.Project{Path: filepath.Join(dir, "project")}
+	spec := filepath.Join(project.Path, core.ProjectLibraryFolder, "Specs", "spec.bin")
+	_ = os.MkdirAll(filepath.Dir(spec), 0755)
+	_ = ioutil.WriteFile(spec, []byte("spec"), 0644)
+	_ = extfs.Set(spec, core.FileAttr{Owner: "alice"}, true)
+	_ = extfs.Set(spec, fed.FedAttr{IsTracked: true}, true)
+	_ = extfs.Set(spec, transport.FileAttr{ModTime: time.Now(), PushHash: []byte("hash")}, false)
+
+	assert.Nil(t, DeleteFile(project, "/Specs", "bob"))
+	assert.NoFileExists(t, spec)
+	assert.False(t, extfs.Get(spec, &core.FileAttr{}))
+	assert.Contains(t, extfs.List(filepath.Join(project.Path, core.ProjectLibraryFolder), &fed.FedAttr{}),
+		filepath.Join("Specs", "spec.bin"))
+
+	items, err := ListTrash(project)
+	assert.Nil(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "/Specs", items[0].Path)
+	assert.True(t, items[0].Dir)
+	assert.Equal(t, "bob", items[0].User)
+
+	_, err = Restore(project, items[0].ID)
+	assert.Nil(t, err)
+	assert.FileExists(t, spec)
+	var attr core.FileAttr
+	assert.True(t, extfs.Get(spec, &attr))
+	assert.Equal(t, "alice", attr.Owner)
+	var fedAttr fed.FedAttr
+	assert.True(t, extfs.Get(spec, &fedAttr))
+	assert.True(t, fedAttr.IsTracked)
+	var pushAttr transport.FileAttr
+	assert.True(t, extfs.Get(spec, &pushAttr))
+	assert.Nil(t, pushAttr.PushHash)
+
+	assert.Nil(t, DeleteFile(project, "/Specs/spec.bin", "bob"))
+	items, _ = ListTrash(project)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "alice", items[0].Owner)
+	trashed := filepath.Join(project.Path, core.ProjectTrashFolder, items[0].ID, "spec.bin")
+	assert.True(t, extfs.Get(trashed, &core.FileAttr{}))
+	assert.False(t, extfs.Get(spec, &core.FileAttr{}))
+
+	project.Config.Public.TrashRetention = 1
+	p := filepath.Join(project.Path, core.ProjectTrashFolder, items[0].ID+".json")
+	item := items[0]
+	item.Deleted = time.Now().AddDate(0, 0, -2)
+	_ = fs.WriteJSON(p, &item)
+	_ = os.Chtimes(p, item.Deleted, item.Deleted)
+	items, _ = ListTrash(project)
+	assert.Len(t, items, 0)
+	assert.False(t, extfs.Get(trashed, &core.FileAttr{}))
+	assert.False(t, extfs.Get(trashed, &fed.FedAttr{}))
+	assert.False(t, extfs.Get(spec, &core.FileAttr{}))
+	assert.Equal(t, core.ErrNoFound, Purge(project, "../x"))
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/code-to-go/fed/extfs"
 	"github.com/hashicorp/go-version"
 	"github.com/sirupsen/logrus"
-	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -28,7 +27,7 @@ func archiveFile(project *core.Project, path string) (string, error) {
 		logrus.Errorf("Cannot archive file %s: %v", source, err)
 		return "", err
 	}
-	extfs.Move(source, dest)
+	moveAttrs(source, dest, false)
 
 	logrus.Debugf("Archived file %s to %s", source, dest)
 	return dest, nil
@@ -127,25 +126,19 @@ func IncreaseVersion(project *core.Project, path string, owner string, public bo
 	path_ := filepath.Join(dir, fmt.Sprintf("%s%s%s", prefix, ver_, ext))
 
 	fullPath := filepath.Join(project.Path, core.ProjectLibraryFolder, path)
-	r, err := os.Open(fullPath)
-	if err != nil {
+	if err := checkLock(fullPath, owner); err != nil {
 		return "", err
 	}
-	defer r.Close()
 
 	fullPath_ := filepath.Join(project.Path, core.ProjectLibraryFolder, path_)
-	w, err := os.Create(fullPath_)
-	if err != nil {
-		return "", err
+	if public || !core.CanShare(fullPath) {
+		err = core.CopyFile(fullPath, fullPath_)
+	} else {
+		err = core.ShareFile(project, fullPath, fullPath_)
 	}
-	defer w.Close()
-
-	_, err = io.Copy(w, r)
 	if err != nil {
 		return "", err
 	}
-
-	_, _ = archiveFile(project, path)
 	_ = extfs.Set(fullPath_, core.FileAttr{
 		Owner:  owner,
 		Public: public,
@@ -154,7 +147,15 @@ func IncreaseVersion(project *core.Project, path string, owner string, public bo
 
 	archivePath := filepath.Join(project.Path, core.ProjectArchiveFolder, path)
 	_ = os.MkdirAll(filepath.Dir(archivePath), 0755)
-	_ = os.Rename(fullPath, archivePath)
+	if _, err := archiveFile(project, path); err != nil {
+		return "", err
+	}
+	// archived versions do not change, so they share the content in the blob store even when public
+	if _, err := core.ImportFile(project, archivePath); err != nil {
+		logrus.Warnf("cannot import %s in blob store: %v", archivePath, err)
+	}
 
+	queueIndex(project, path)
+	queueIndex(project, path_)
 	return path_, nil
 }
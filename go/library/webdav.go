@@ -0,0 +1,266 @@
+package library
+
+import (
+	"almost-scrum/core"
+	"almost-scrum/fs"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/code-to-go/fed/extfs"
+	"golang.org/x/net/webdav"
+)
+
+// DavFS exposes the library of a project as a WebDAV file system. Changes go through the library
+// functions on behalf of the user, so that owners, locks, the trash, the index and the versions
+// work as with the web UI.
+type DavFS struct {
+	project *core.Project
+	user    string
+}
+
+// NewDavFS returns the WebDAV file system of the library for a user
+func NewDavFS(project *core.Project, user string) *DavFS {
+	return &DavFS{project: project, user: user}
+}
+
+// davInfo is the information about a library item in a folder listing
+type davInfo struct {
+	item Item
+}
+
+func (i davInfo) Name() string { return i.item.Name }
+
+// Size of folders is 0, since the size of items is the number of files in the folder
+func (i davInfo) Size() int64 {
+	if i.item.Dir {
+		return 0
+	}
+	return i.item.Size
+}
+
+func (i davInfo) Mode() os.FileMode {
+	if i.item.Dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (i davInfo) ModTime() time.Time { return i.item.ModTime }
+func (i davInfo) IsDir() bool        { return i.item.Dir }
+func (i davInfo) Sys() interface{}   { return nil }
+
+// davFile is a file or a folder opened for reading. The content of folders is the one returned by List,
+// i.e. only the latest version of each document.
+type davFile struct {
+	*os.File
+	dav   *DavFS
+	name  string
+	infos []os.FileInfo
+}
+
+func (f *davFile) Readdir(count int) ([]os.FileInfo, error) {
+	if f.infos == nil {
+		if info, err := f.File.Stat(); err != nil || !info.IsDir() {
+			return nil, os.ErrInvalid
+		}
+		items, err := List(f.dav.project, f.name)
+		if err != nil {
+			return nil, err
+		}
+		f.infos = make([]os.FileInfo, 0, len(items))
+		for _, item := range items {
+			f.infos = append(f.infos, davInfo{item})
+		}
+	}
+	if count <= 0 {
+		infos := f.infos
+		f.infos = f.infos[len(f.infos):]
+		return infos, nil
+	}
+	if len(f.infos) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(f.infos) {
+		count = len(f.infos)
+	}
+	infos := f.infos[:count]
+	f.infos = f.infos[count:]
+	return infos, nil
+}
+
+func (f *davFile) Write([]byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+// davWriter collects the content of a file in a temporary file and sets it in the library on close
+type davWriter struct {
+	*os.File
+	dav  *DavFS
+	name string
+}
+
+func (w *davWriter) Readdir(int) ([]os.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+// Stat returns the information of the temporary file with the name of the file in the library
+func (w *davWriter) Stat() (os.FileInfo, error) {
+	info, err := w.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return davInfo{Item{Name: filepath.Base(w.name), Size: info.Size(), ModTime: info.ModTime()}}, nil
+}
+
+// Close writes the content in the library. When the file is an existing document with a version,
+// e.g. Spec~0.3.docx, the next version is created and the content goes in the new version.
+func (w *davWriter) Close() error {
+	defer os.Remove(w.File.Name())
+	if _, err := w.File.Seek(0, io.SeekStart); err != nil {
+		_ = w.File.Close()
+		return err
+	}
+
+	name := w.name
+	absPath, _ := AbsPath(w.dav.project, name)
+	var attr core.FileAttr
+	if _, err := os.Stat(absPath); err == nil {
+		extfs.Get(absPath, &attr)
+		if _, _, version, _, err := fs.ParsePath(name); err == nil && version != "" {
+			if name, err = IncreaseVersion(w.dav.project, name, w.dav.user, attr.Public); err != nil {
+				_ = w.File.Close()
+				return err
+			}
+		}
+	}
+	_, err := SetFileInLibrary(w.dav.project, name, w.File, w.dav.user, attr.Public)
+	if closeErr := w.File.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// resolve cleans a WebDAV name and returns it with its location in the library. Names that are
+// outside the library are rejected, since the WebDAV handler leaves the cleaning to the file system.
+func (d *DavFS) resolve(name string) (string, string, error) {
+	name = path.Clean("/" + name)
+	root, err := AbsPath(d.project, "")
+	if err != nil {
+		return "", "", err
+	}
+	absPath := filepath.Join(root, filepath.FromSlash(name))
+	if !isInside(root, absPath) {
+		return "", "", os.ErrPermission
+	}
+	return name, absPath, nil
+}
+
+func (d *DavFS) Mkdir(_ context.Context, name string, _ os.FileMode) error {
+	name, absPath, err := d.resolve(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(absPath); err == nil {
+		return os.ErrExist
+	}
+	if _, err := os.Stat(filepath.Dir(absPath)); err != nil {
+		return err
+	}
+	return CreateFolder(d.project, name, d.user)
+}
+
+func (d *DavFS) OpenFile(_ context.Context, name string, flag int, _ os.FileMode) (webdav.File, error) {
+	name, absPath, err := d.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) == 0 {
+		file, err := os.Open(absPath)
+		if err != nil {
+			return nil, err
+		}
+		return &davFile{File: file, dav: d, name: name}, nil
+	}
+
+	info, err := os.Stat(absPath)
+	switch {
+	case err == nil && info.IsDir():
+		return nil, os.ErrInvalid
+	case err == nil && flag&os.O_EXCL != 0:
+		return nil, os.ErrExist
+	case os.IsNotExist(err) && flag&os.O_CREATE == 0:
+		return nil, err
+	}
+	if err := checkLock(absPath, d.user); err != nil {
+		return nil, err
+	}
+
+	tmp, err := ioutil.TempFile("", "ash-dav-")
+	if err != nil {
+		return nil, err
+	}
+	if info != nil && flag&os.O_TRUNC == 0 {
+		if err := copyContent(absPath, tmp); err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+			return nil, err
+		}
+		if flag&os.O_APPEND == 0 {
+			_, _ = tmp.Seek(0, io.SeekStart)
+		}
+	}
+	return &davWriter{File: tmp, dav: d, name: name}, nil
+}
+
+func copyContent(source string, w io.Writer) error {
+	r, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// RemoveAll moves the file or the folder to the trash
+func (d *DavFS) RemoveAll(_ context.Context, name string) error {
+	name, _, err := d.resolve(name)
+	if err != nil {
+		return err
+	}
+	if name == "/" {
+		return os.ErrPermission
+	}
+	if err := DeleteFile(d.project, name, d.user); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (d *DavFS) Rename(_ context.Context, oldName, newName string) error {
+	oldName, _, err := d.resolve(oldName)
+	if err != nil {
+		return err
+	}
+	newName, _, err = d.resolve(newName)
+	if err != nil {
+		return err
+	}
+	if oldName == "/" || newName == "/" {
+		return os.ErrPermission
+	}
+	return MoveFile(d.project, oldName, newName, d.user)
+}
+
+func (d *DavFS) Stat(_ context.Context, name string) (os.FileInfo, error) {
+	_, absPath, err := d.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(absPath)
+}
@@ -0,0 +1,114 @@
+package library
+
+import (
+	"almost-scrum/core"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/code-to-go/fed"
+	"github.com/code-to-go/fed/extfs"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/webdav"
+)
+
+// davFed tracks files without a federation
+type davFed struct {
+	fed.Connection
+}
+
+func (davFed) SetTracked(string, bool) error { return nil }
+
+func TestDavFS(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "ash-dav")
+	defer os.RemoveAll(dir)
+	extfs.Init(filepath.Join(dir, "extfs"))
+
+	project := &core.Project{Path: filepath.Join(dir, "project"), Fed: davFed{}}
+	for _, folder := range []string{core.ProjectLibraryFolder, core.ProjectArchiveFolder} {
+		_ = os.MkdirAll(filepath.Join(project.Path, folder), 0755)
+	}
+	handler := &webdav.Handler{FileSystem: NewDavFS(project, "alice"), LockSystem: webdav.NewMemLS()}
+	do := func(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		for i := 0; i+1 < len(headers); i += 2 {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	libraryPath := func(path string) string {
+		return filepath.Join(project.Path, core.ProjectLibraryFolder, path)
+	}
+
+	assert.Equal(t, http.StatusCreated, do("PUT", "/Spec~0.3.txt", "first").Code)
+	var attr core.FileAttr
+	assert.True(t, extfs.Get(libraryPath("Spec~0.3.txt"), &attr))
+	assert.Equal(t, "alice", attr.Owner)
+
+	// writing over a versioned document creates the next version
+	assert.Equal(t, http.StatusCreated, do("PUT", "/Spec~0.3.txt", "second").Code)
+	assert.NoFileExists(t, libraryPath("Spec~0.3.txt"))
+	data, _ := ioutil.ReadFile(libraryPath("Spec~0.4.txt"))
+	assert.Equal(t, "second", string(data))
+	data, _ = ioutil.ReadFile(filepath.Join(project.Path, core.ProjectArchiveFolder, "Spec~0.3.txt"))
+	assert.Equal(t, "first", string(data))
+	assert.Equal(t, "second", do("GET", "/Spec~0.4.txt", "").Body.String())
+
+	_, err := CheckOut(project, "/Spec~0.4.txt", "bob", 0)
+	assert.Nil(t, err)
+	assert.NotEqual(t, http.StatusCreated, do("PUT", "/Spec~0.4.txt", "third").Code)
+	assert.NotEqual(t, http.StatusNoContent, do("DELETE", "/Spec~0.4.txt", "").Code)
+	assert.FileExists(t, libraryPath("Spec~0.4.txt"))
+	assert.Nil(t, Unlock(project, "/Spec~0.4.txt", "bob"))
+
+	assert.Equal(t, http.StatusCreated, do("MKCOL", "/Specs", "").Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, do("MKCOL", "/Specs", "").Code)
+	w := do("MOVE", "/Spec~0.4.txt", "", "Destination", "/Specs/Spec~0.4.txt")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.FileExists(t, libraryPath("Specs/Spec~0.4.txt"))
+
+	w = do("PROPFIND", "/Specs", "", "Depth", "1")
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "Spec~0.4.txt")
+
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/Specs", "").Code)
+	assert.NoDirExists(t, libraryPath("Specs"))
+	items, _ := ListTrash(project)
+	assert.Len(t, items, 1)
+}
+
+func TestDavFSTraversal(t *testing.T) {
+	dir, _ := ioutil.TempDir(os.TempDir(), "ash-dav")
+	defer os.RemoveAll(dir)
+	extfs.Init(filepath.Join(dir, "extfs"))
+
+	project := &core.Project{Path: filepath.Join(dir, "project"), Fed: davFed{}}
+	_ = os.MkdirAll(filepath.Join(project.Path, core.ProjectLibraryFolder), 0755)
+	secret := filepath.Join(dir, "secret.txt")
+	_ = ioutil.WriteFile(secret, []byte("secret"), 0644)
+
+	ctx := context.Background()
+	dav := NewDavFS(project, "alice")
+	_, err := dav.Stat(ctx, "/../../secret.txt")
+	assert.True(t, os.IsNotExist(err))
+	_, err = dav.OpenFile(ctx, "/../../secret.txt", os.O_RDONLY, 0)
+	assert.True(t, os.IsNotExist(err))
+	assert.NotNil(t, dav.Rename(ctx, "/../../secret.txt", "/stolen.txt"))
+	assert.NotNil(t, dav.Rename(ctx, "../../secret.txt", "/stolen.txt"))
+	assert.FileExists(t, secret)
+	assert.NoFileExists(t, filepath.Join(project.Path, core.ProjectLibraryFolder, "stolen.txt"))
+
+	handler := &webdav.Handler{FileSystem: dav, LockSystem: webdav.NewMemLS()}
+	r := httptest.NewRequest("GET", "/../../secret.txt", nil)
+	r.URL.Path = "/../../secret.txt"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.NotEqual(t, "secret", w.Body.String())
+}
@@ -0,0 +1,318 @@
+package query
+
+import (
+	"almost-scrum/core"
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatCSV        = "csv"
+	FormatJSONLines  = "jsonl"
+	FormatMarkdown   = "md"
+	FormatXLSX       = "xlsx"
+	DescriptionField = "description"
+)
+
+// ExportContentTypes maps the export formats to their MIME types
+var ExportContentTypes = map[string]string{
+	FormatCSV:       "text/csv; charset=utf-8",
+	FormatJSONLines: "application/x-ndjson",
+	FormatMarkdown:  "text/markdown; charset=utf-8",
+	FormatXLSX:      "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatICS:       "text/calendar; charset=utf-8",
+}
+
+// ErrInvalidFormat is returned when an export format is not supported
+var ErrInvalidFormat = errors.New("invalid export format")
+
+// ExportColumns returns the default columns for an export: board, name, type and then the properties
+// of the models in the query (or of all models) in the order they are defined.
+func ExportColumns(project *core.Project, q Query) []string {
+	columns := []string{"board", "name", "Type"}
+
+	types := QueryTypes(project, q.WhereTypes)
+	for _, model := range project.Models {
+		if len(q.WhereTypes) > 0 && !core.HasStringInSlice(types, model.Name) {
+			continue
+		}
+		for _, propertyDef := range model.Properties {
+			if !core.HasStringInSlice(columns, propertyDef.Name) {
+				columns = append(columns, propertyDef.Name)
+			}
+		}
+	}
+	return append(columns, "modTime")
+}
+
+func columnValue(ref *TaskRef, column string) string {
+	if column == DescriptionField {
+		return ref.Task.Description
+	}
+	return fieldValue(ref, column)
+}
+
+type exportWriter interface {
+	writeHeader(columns []string) error
+	writeRow(values []string) error
+	close() error
+}
+
+func newExportWriter(format string, w io.Writer) (exportWriter, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatJSONLines:
+		return &jsonLinesWriter{w: w}, nil
+	case FormatMarkdown:
+		return &markdownWriter{w: w}, nil
+	case FormatXLSX:
+		return &xlsxWriter{zip: zip.NewWriter(w)}, nil
+	default:
+		return nil, ErrInvalidFormat
+	}
+}
+
+type exportEntry struct {
+	info   core.TaskInfo
+	values []string
+	id     string
+}
+
+// Export writes the tasks that match the query in the given format. Only the sort values of the
+// tasks are kept in memory: tasks are read a first time to select and sort them and a second time
+// when they are written. Cursor and aggregations are ignored; Limit is applied. Columns do not apply
+// to the ics format, which does not include the holidays.
+func Export(project *core.Project, q Query, format string, columns []string, w io.Writer) error {
+	if format == FormatICS {
+		return ExportICS(project, q, false, w)
+	}
+	writer, err := newExportWriter(format, w)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		columns = ExportColumns(project, q)
+	}
+
+	m, infos, err := prepareQuery(project, q)
+	if err != nil {
+		return err
+	}
+	orderBy := getOrder(q.OrderBy)
+
+	var entries []exportEntry
+	for _, info := range infos {
+		ref, err := readTaskRef(project, info)
+		if err != nil {
+			m.addReadError(project, info, err)
+			continue
+		}
+		if m.match(ref) {
+			entries = append(entries, exportEntry{
+				info:   info,
+				values: sortValues(ref, orderBy),
+				id:     getId("", info.Board, info.Name),
+			})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return compareKeys(orderBy, entries[i].values, entries[i].id, entries[j].values, entries[j].id) < 0
+	})
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+
+	if err := writer.writeHeader(columns); err != nil {
+		return err
+	}
+	values := make([]string, len(columns))
+	for _, entry := range entries {
+		ref, err := readTaskRef(project, entry.info)
+		if core.IsErr(err, "cannot read task %s/%s for export", entry.info.Board, entry.info.Name) {
+			continue
+		}
+		for i, column := range columns {
+			values[i] = columnValue(ref, column)
+		}
+		if err := writer.writeRow(values); err != nil {
+			return err
+		}
+	}
+	return writer.close()
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) writeHeader(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) writeRow(values []string) error {
+	return c.w.Write(values)
+}
+
+func (c *csvWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonLinesWriter writes a JSON object for each task with the fields in the order of the columns
+type jsonLinesWriter struct {
+	w       io.Writer
+	columns []string
+}
+
+func (j *jsonLinesWriter) writeHeader(columns []string) error {
+	j.columns = make([]string, len(columns))
+	for i, column := range columns {
+		key, _ := json.Marshal(column)
+		j.columns[i] = string(key)
+	}
+	return nil
+}
+
+func (j *jsonLinesWriter) writeRow(values []string) error {
+	var line strings.Builder
+	line.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			line.WriteByte(',')
+		}
+		v, _ := json.Marshal(value)
+		line.WriteString(j.columns[i])
+		line.WriteByte(':')
+		line.Write(v)
+	}
+	line.WriteString("}\n")
+	_, err := io.WriteString(j.w, line.String())
+	return err
+}
+
+func (j *jsonLinesWriter) close() error {
+	return nil
+}
+
+type markdownWriter struct {
+	w io.Writer
+}
+
+var markdownEscape = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+
+func (m *markdownWriter) writeLine(cells []string) error {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = markdownEscape.Replace(cell)
+	}
+	_, err := fmt.Fprintf(m.w, "| %s |\n", strings.Join(escaped, " | "))
+	return err
+}
+
+func (m *markdownWriter) writeHeader(columns []string) error {
+	if err := m.writeLine(columns); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(m.w, "|%s\n", strings.Repeat(" --- |", len(columns)))
+	return err
+}
+
+func (m *markdownWriter) writeRow(values []string) error {
+	return m.writeLine(values)
+}
+
+func (m *markdownWriter) close() error {
+	return nil
+}
+
+// xlsxWriter writes a workbook with a single sheet. The sheet is the last file in the archive so
+// that rows are streamed without keeping them in memory.
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Tasks" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func (x *xlsxWriter) writeHeader(columns []string) error {
+	for _, part := range xlsxParts {
+		w, err := x.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, part.content); err != nil {
+			return err
+		}
+	}
+
+	sheet, err := x.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	x.sheet = sheet
+	_, err = io.WriteString(x.sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return err
+	}
+	return x.writeRow(columns)
+}
+
+func (x *xlsxWriter) writeRow(values []string) error {
+	x.row++
+	var row strings.Builder
+	_, _ = fmt.Fprintf(&row, `<row r="%d">`, x.row)
+	for _, value := range values {
+		if v, err := strconv.ParseFloat(value, 64); err == nil && x.row > 1 && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			_, _ = fmt.Fprintf(&row, `<c><v>%s</v></c>`, value)
+			continue
+		}
+		row.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		_ = xml.EscapeText(&row, []byte(value))
+		row.WriteString(`</t></is></c>`)
+	}
+	row.WriteString(`</row>`)
+	_, err := io.WriteString(x.sheet, row.String())
+	return err
+}
+
+func (x *xlsxWriter) close() error {
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
@@ -0,0 +1,38 @@
+package query
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func writeRows(t *testing.T, format string) []byte {
+	var buf bytes.Buffer
+	w, err := newExportWriter(format, &buf)
+	assert.Nil(t, err)
+	assert.Nil(t, w.writeHeader([]string{"name", "Points"}))
+	assert.Nil(t, w.writeRow([]string{"1.Login | Logout", "5"}))
+	assert.Nil(t, w.close())
+	return buf.Bytes()
+}
+
+func TestExportWriters(t *testing.T) {
+	assert.Equal(t, "name,Points\n1.Login | Logout,5\n", string(writeRows(t, FormatCSV)))
+	assert.Equal(t, `{"name":"1.Login | Logout","Points":"5"}`+"\n", string(writeRows(t, FormatJSONLines)))
+	assert.Equal(t, "| name | Points |\n| --- | --- |\n| 1.Login \\| Logout | 5 |\n",
+		string(writeRows(t, FormatMarkdown)))
+
+	data := writeRows(t, FormatXLSX)
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+	assert.Len(t, r.File, 5)
+	sheet, err := r.File[4].Open()
+	assert.Nil(t, err)
+	content, _ := ioutil.ReadAll(sheet)
+	assert.Contains(t, string(content), `<row r="2"><c t="inlineStr"><is><t xml:space="preserve">1.Login | Logout</t></is></c><c><v>5</v></c></row>`)
+
+	_, err = newExportWriter("pdf", &bytes.Buffer{})
+	assert.Equal(t, ErrInvalidFormat, err)
+}
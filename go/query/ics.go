@@ -0,0 +1,177 @@
+package query
+
+import (
+	"almost-scrum/core"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FormatICS is the iCalendar format for calendar clients
+const FormatICS = "ics"
+
+// DueProperty is the due date of a task without Start and End
+const DueProperty = "Due"
+
+const (
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405Z"
+)
+
+var icsEscape = strings.NewReplacer("\\", "\\\\", ";", "\\;", ",", "\\,", "\r\n", "\\n", "\n", "\\n")
+
+// icsWriter writes the lines of an iCalendar file folded at 75 octets and with CRLF endings
+type icsWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (i *icsWriter) line(format string, args ...interface{}) {
+	if i.err != nil {
+		return
+	}
+	l := fmt.Sprintf(format, args...)
+	var folded strings.Builder
+	for limit := 75; len(l) > limit; limit = 74 {
+		cut := limit
+		for cut > 1 && (l[cut]&0xC0) == 0x80 {
+			cut--
+		}
+		folded.WriteString(l[0:cut])
+		folded.WriteString("\r\n ")
+		l = l[cut:]
+	}
+	folded.WriteString(l)
+	folded.WriteString("\r\n")
+	_, i.err = io.WriteString(i.w, folded.String())
+}
+
+// icsValue returns the iCalendar value of a date with the VALUE parameter. Dates without
+// time are all-day dates.
+func icsValue(s string) (string, time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(core.DateLayout, s); err == nil {
+		return ";VALUE=DATE:" + t.Format(icsDate), t, true
+	}
+	if t, ok := parseDate(s, time.Now()); ok {
+		return ":" + t.UTC().Format(icsDateTime), t, true
+	}
+	return "", time.Time{}, false
+}
+
+func (i *icsWriter) writeTask(project *core.Project, ref *TaskRef) {
+	properties := ref.Task.Properties
+	start, _, hasStart := icsValue(properties["Start"])
+	end, endTime, hasEnd := icsValue(properties["End"])
+	due, _, hasDue := icsValue(properties[DueProperty])
+	if !hasStart && !hasEnd && !hasDue {
+		return
+	}
+
+	component := "VTODO"
+	if hasStart && hasEnd {
+		component = "VEVENT"
+		// DTEND is exclusive while End is the last day of the task
+		if strings.HasPrefix(end, ";VALUE=DATE") {
+			end = ";VALUE=DATE:" + endTime.AddDate(0, 0, 1).Format(icsDate)
+		}
+	}
+
+	i.line("BEGIN:%s", component)
+	i.line("UID:%s/%s/%s", project.Config.UUID, ref.Board, icsEscape.Replace(ref.Name))
+	i.line("DTSTAMP:%s", ref.ModTime.UTC().Format(icsDateTime))
+	i.line("SUMMARY:%s", icsEscape.Replace(ref.Name))
+	switch {
+	case component == "VEVENT":
+		i.line("DTSTART%s", start)
+		i.line("DTEND%s", end)
+	case hasDue:
+		i.line("DUE%s", due)
+	case hasEnd:
+		i.line("DUE%s", end)
+	default:
+		i.line("DTSTART%s", start)
+	}
+	if owner := properties["Owner"]; owner != "" {
+		i.line("X-ALMOST-SCRUM-OWNER:%s", icsEscape.Replace(owner))
+	}
+	if status := properties["Status"]; status != "" {
+		i.line("CATEGORIES:%s", icsEscape.Replace(strings.TrimPrefix(status, "#")))
+	}
+	if ref.Task.Description != "" {
+		i.line("DESCRIPTION:%s", icsEscape.Replace(ref.Task.Description))
+	}
+	i.line("END:%s", component)
+}
+
+func (i *icsWriter) writeHoliday(project *core.Project, holiday core.Holiday) {
+	day, err := time.Parse(core.DateLayout, holiday.Date)
+	if err != nil {
+		return
+	}
+	i.line("BEGIN:VEVENT")
+	i.line("UID:%s/holiday/%s", project.Config.UUID, holiday.Date)
+	i.line("DTSTAMP:%s", day.Format(icsDateTime))
+	i.line("SUMMARY:%s", icsEscape.Replace(holiday.Name))
+	i.line("DTSTART;VALUE=DATE:%s", day.Format(icsDate))
+	i.line("DTEND;VALUE=DATE:%s", day.AddDate(0, 0, 1).Format(icsDate))
+	i.line("TRANSP:TRANSPARENT")
+	i.line("END:VEVENT")
+}
+
+func (i *icsWriter) writeSprint(project *core.Project, board string, properties core.BoardProperties) {
+	start, err1 := time.Parse(core.DateLayout, strings.TrimSpace(properties.Start))
+	end, err2 := time.Parse(core.DateLayout, strings.TrimSpace(properties.End))
+	if err1 != nil || err2 != nil || end.Before(start) {
+		return
+	}
+	i.line("BEGIN:VEVENT")
+	i.line("UID:%s/sprint/%s", project.Config.UUID, icsEscape.Replace(board))
+	i.line("DTSTAMP:%s", start.Format(icsDateTime))
+	i.line("SUMMARY:%s", icsEscape.Replace(board))
+	i.line("DTSTART;VALUE=DATE:%s", start.Format(icsDate))
+	i.line("DTEND;VALUE=DATE:%s", end.AddDate(0, 0, 1).Format(icsDate))
+	i.line("TRANSP:TRANSPARENT")
+	i.line("END:VEVENT")
+}
+
+// ExportICS writes the tasks that match the query and have dates in iCalendar format. Tasks with
+// Start and End become events; tasks with only a due date or an end become to-dos. The sprints, i.e.
+// the boards in the query with Start and End, are added as all-day events and so are the holidays of
+// the project when holidays is true.
+func ExportICS(project *core.Project, q Query, holidays bool, w io.Writer) error {
+	refs, _, err := matchTasks(project, q)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(refs, func(i, j int) bool {
+		return getId("", refs[i].Board, refs[i].Name) < getId("", refs[j].Board, refs[j].Name)
+	})
+
+	i := &icsWriter{w: w}
+	i.line("BEGIN:VCALENDAR")
+	i.line("VERSION:2.0")
+	i.line("PRODID:-//Almost Scrum//%s//EN", icsEscape.Replace(project.Config.Public.Name))
+	i.line("X-WR-CALNAME:%s", icsEscape.Replace(project.Config.Public.Name))
+	for _, ref := range refs {
+		i.writeTask(project, ref)
+	}
+	boards, _ := core.ListBoards(project)
+	for _, board := range boards {
+		if q.WhereBoardIs != nil && !matchBoard(board, q.WhereBoardIs) {
+			continue
+		}
+		if properties, err := core.GetBoardProperties(project, board); err == nil {
+			i.writeSprint(project, board, properties)
+		}
+	}
+	if holidays {
+		for _, holiday := range project.Config.Public.Calendar.Holidays {
+			i.writeHoliday(project, holiday)
+		}
+	}
+	i.line("END:VCALENDAR")
+	return i.err
+}
@@ -0,0 +1,58 @@
+package query
+
+import (
+	"almost-scrum/core"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestWriteTaskICS(t *testing.T) {
+	project := &core.Project{}
+	project.Config.UUID = "uuid"
+	var b bytes.Buffer
+	w := &icsWriter{w: &b}
+
+	w.writeTask(project, &TaskRef{Board: "backlog", Name: "1.Design, review", Task: core.Task{
+		Properties: map[string]string{"Start": "2021-03-01", "End": "2021-03-01", "Owner": "@bob"},
+	}})
+	w.writeTask(project, &TaskRef{Board: "backlog", Name: "2.Release", Task: core.Task{
+		Properties:  map[string]string{"Due": "2021-03-10"},
+		Description: strings.Repeat("long ", 30),
+	}})
+	w.writeTask(project, &TaskRef{Board: "backlog", Name: "4.Build", Task: core.Task{
+		Properties: map[string]string{"Start": "2021-03-02", "End": "2021-03-04"},
+	}})
+	w.writeTask(project, &TaskRef{Board: "backlog", Name: "3.Undated", Task: core.Task{
+		Properties: map[string]string{},
+	}})
+
+	ics := b.String()
+	assert.Nil(t, w.err)
+	assert.Contains(t, ics, "BEGIN:VEVENT\r\nUID:uuid/backlog/1.Design\\, review\r\n")
+	assert.Contains(t, ics, "DTSTART;VALUE=DATE:20210301\r\nDTEND;VALUE=DATE:20210302\r\n")
+	assert.Contains(t, ics, "DTSTART;VALUE=DATE:20210302\r\nDTEND;VALUE=DATE:20210305\r\n")
+	assert.Contains(t, ics, "BEGIN:VTODO\r\n")
+	assert.Contains(t, ics, "DUE;VALUE=DATE:20210310\r\n")
+	assert.NotContains(t, ics, "3.Undated")
+	for _, line := range strings.Split(ics, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+}
+
+func TestWriteSprintICS(t *testing.T) {
+	project := &core.Project{}
+	project.Config.UUID = "uuid"
+	var b bytes.Buffer
+	w := &icsWriter{w: &b}
+
+	w.writeSprint(project, "sprint-1", core.BoardProperties{Start: "2021-03-01", End: "2021-03-12"})
+	w.writeSprint(project, "backlog", core.BoardProperties{})
+
+	ics := b.String()
+	assert.Nil(t, w.err)
+	assert.Contains(t, ics, "UID:uuid/sprint/sprint-1\r\n")
+	assert.Contains(t, ics, "DTSTART;VALUE=DATE:20210301\r\nDTEND;VALUE=DATE:20210313\r\n")
+	assert.NotContains(t, ics, "backlog")
+}
@@ -0,0 +1,439 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError is an error in a textual query. Pos is the offset in bytes where the error is found.
+type SyntaxError struct {
+	Pos int    `json:"pos"`
+	Msg string `json:"msg"`
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+type parser struct {
+	text string
+	pos  int
+}
+
+var operators = []string{">=", "<=", "!=", ">", "<", "=", ":", "~"}
+
+// item is a term of the query: a word, a phrase, a condition on a field or a group of conditions
+type item struct {
+	pos    int
+	word   string
+	phrase string
+	field  string
+	op     string
+	value  string
+	group  *Condition
+}
+
+// Parse compiles a textual query into a Query. The syntax is a list of terms separated by spaces:
+//  type:feature          tasks of type feature
+//  board:sprint-*        tasks in boards matching the pattern
+//  owner:@bob,@alice     property Owner is any of the values
+//  status:!#Done         property Status is not #Done
+//  points>=5             comparison on a property (>, >=, <, <=, =, !=)
+//  end<today+7d          comparison with a date relative to today (d, w, m, y)
+//  points:3..8           property between two values
+//  release:v1*           property starts with v1
+//  title~^Login          property matches the regular expression
+//  has:epic              property Epic is set
+//  no:epic               property Epic is missing or empty
+//  "login bug"           tasks that contain the phrase
+//  login                 tasks that contain the word
+//  sort:-points,name     order by Points descending and then by name
+//  group:owner           group the tasks by Owner
+//  limit:20              return at most 20 tasks
+// Conditions are combined with AND. OR, NOT and parentheses combine conditions on properties,
+// e.g. (owner:@bob OR owner:@alice) NOT status:#Done
+func Parse(text string) (Query, error) {
+	var q Query
+	p := parser{text: text}
+
+	alternatives, err := p.parseOr()
+	if err != nil {
+		return q, err
+	}
+	if p.pos < len(p.text) {
+		return q, &SyntaxError{Pos: p.pos, Msg: "unexpected ')'"}
+	}
+
+	if len(alternatives) > 1 {
+		condition, err := toCondition(alternatives)
+		if err != nil {
+			return q, err
+		}
+		q.addWhere(condition)
+		return q, nil
+	}
+
+	for _, it := range alternatives[0] {
+		switch {
+		case it.group != nil:
+			q.addWhere(*it.group)
+		case it.phrase != "":
+			q.Keys = append(q.Keys, strings.Fields(it.phrase)...)
+			q.Phrases = append(q.Phrases, it.phrase)
+		case it.word != "":
+			q.Keys = append(q.Keys, it.word)
+		default:
+			if err := q.addCondition(it.field, it.op, it.value, it.pos); err != nil {
+				return q, err
+			}
+		}
+	}
+	return q, nil
+}
+
+func (p *parser) atKeyword(keyword string) bool {
+	if !strings.HasPrefix(p.text[p.pos:], keyword) {
+		return false
+	}
+	end := p.pos + len(keyword)
+	return end == len(p.text) || p.text[end] == ' ' || p.text[end] == '\t' || p.text[end] == '('
+}
+
+func (p *parser) parseOr() ([][]item, error) {
+	var alternatives [][]item
+	for {
+		p.skipSpaces()
+		start := p.pos
+		items, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 && (len(alternatives) > 0 || p.pos < len(p.text)) {
+			return nil, &SyntaxError{Pos: start, Msg: "missing condition"}
+		}
+		alternatives = append(alternatives, items)
+
+		p.skipSpaces()
+		if !p.atKeyword("OR") {
+			return alternatives, nil
+		}
+		p.pos += len("OR")
+	}
+}
+
+func (p *parser) parseAnd() ([]item, error) {
+	var items []item
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.text) || p.text[p.pos] == ')' || p.atKeyword("OR") {
+			return items, nil
+		}
+		it, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if it != nil {
+			items = append(items, *it)
+		}
+	}
+}
+
+func (p *parser) parseUnary() (*item, error) {
+	start := p.pos
+	if p.atKeyword("NOT") {
+		p.pos += len("NOT")
+		p.skipSpaces()
+		if p.pos >= len(p.text) || p.text[p.pos] == ')' {
+			return nil, &SyntaxError{Pos: start, Msg: "missing condition after NOT"}
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if operand == nil {
+			return nil, &SyntaxError{Pos: start, Msg: "missing condition after NOT"}
+		}
+		condition, err := operand.toCondition()
+		if err != nil {
+			return nil, err
+		}
+		return &item{pos: start, group: &Condition{Not: &condition}}, nil
+	}
+
+	if p.text[p.pos] == '(' {
+		p.pos++
+		alternatives, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.text) || p.text[p.pos] != ')' {
+			return nil, &SyntaxError{Pos: start, Msg: "missing ')'"}
+		}
+		p.pos++
+		condition, err := toCondition(alternatives)
+		if err != nil {
+			return nil, err
+		}
+		return &item{pos: start, group: &condition}, nil
+	}
+
+	return p.parseTerm()
+}
+
+func (p *parser) parseTerm() (*item, error) {
+	start := p.pos
+	if p.text[p.pos] == '"' {
+		phrase, err := p.readQuoted()
+		if err != nil {
+			return nil, err
+		}
+		if len(strings.Fields(phrase)) == 0 {
+			return nil, nil
+		}
+		return &item{pos: start, phrase: phrase}, nil
+	}
+
+	name := p.readName()
+	op := p.readOperator()
+	if op == "" {
+		if name == "" {
+			return nil, &SyntaxError{Pos: start, Msg: fmt.Sprintf("unexpected character '%c'", p.text[p.pos])}
+		}
+		return &item{pos: start, word: name}, nil
+	}
+	if name == "" {
+		return nil, &SyntaxError{Pos: start, Msg: fmt.Sprintf("missing field before '%s'", op)}
+	}
+
+	valuePos := p.pos
+	value, err := p.readValue()
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, &SyntaxError{Pos: valuePos, Msg: fmt.Sprintf("missing value for %s", name)}
+	}
+	return &item{pos: start, field: name, op: op, value: value}, nil
+}
+
+// toCondition converts the alternatives of an OR in a condition
+func toCondition(alternatives [][]item) (Condition, error) {
+	conditions := make([]Condition, 0, len(alternatives))
+	for _, items := range alternatives {
+		all := make([]Condition, 0, len(items))
+		for _, it := range items {
+			condition, err := it.toCondition()
+			if err != nil {
+				return Condition{}, err
+			}
+			all = append(all, condition)
+		}
+		if len(all) == 1 {
+			conditions = append(conditions, all[0])
+		} else {
+			conditions = append(conditions, Condition{All: all})
+		}
+	}
+	if len(conditions) == 1 {
+		return conditions[0], nil
+	}
+	return Condition{Any: conditions}, nil
+}
+
+func (it item) toCondition() (Condition, error) {
+	switch {
+	case it.group != nil:
+		return *it.group, nil
+	case it.phrase != "" || it.word != "":
+		return Condition{}, &SyntaxError{Pos: it.pos, Msg: "words cannot be combined with OR, NOT or parentheses"}
+	}
+
+	if strings.ToLower(it.field) == "type" {
+		if it.op != ":" && it.op != "=" {
+			return Condition{}, &SyntaxError{Pos: it.pos, Msg: fmt.Sprintf("operator %s not valid for type", it.op)}
+		}
+		return Condition{Property: &WhereProperty{Name: "Type", ValueIsAnyOf: strings.Split(it.value, ",")}}, nil
+	}
+	where, err := whereProperty(it.field, it.op, it.value, it.pos)
+	if err != nil {
+		return Condition{}, err
+	}
+	return Condition{Property: &where}, nil
+}
+
+func (q *Query) addWhere(condition Condition) {
+	if q.Where == nil {
+		q.Where = &Condition{}
+	}
+	q.Where.All = append(q.Where.All, condition)
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func isNameChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '@' || r == '#' ||
+		r == '.' || r == '*'
+}
+
+func (p *parser) readName() string {
+	start := p.pos
+	for i, r := range p.text[p.pos:] {
+		if !isNameChar(r) {
+			p.pos = start + i
+			return p.text[start:p.pos]
+		}
+	}
+	p.pos = len(p.text)
+	return p.text[start:]
+}
+
+func (p *parser) readOperator() string {
+	for _, op := range operators {
+		if strings.HasPrefix(p.text[p.pos:], op) {
+			p.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *parser) readQuoted() (string, error) {
+	start := p.pos
+	end := strings.IndexByte(p.text[start+1:], '"')
+	if end < 0 {
+		return "", &SyntaxError{Pos: start, Msg: "unterminated quote"}
+	}
+	p.pos = start + end + 2
+	return p.text[start+1 : start+end+1], nil
+}
+
+func (p *parser) readValue() (string, error) {
+	var value strings.Builder
+	for p.pos < len(p.text) && p.text[p.pos] != ' ' && p.text[p.pos] != '\t' && p.text[p.pos] != ')' {
+		if p.text[p.pos] == '"' {
+			quoted, err := p.readQuoted()
+			if err != nil {
+				return "", err
+			}
+			value.WriteString(quoted)
+		} else {
+			value.WriteByte(p.text[p.pos])
+			p.pos++
+		}
+	}
+	return value.String(), nil
+}
+
+// propertyName converts a field in the query to the name of a property, e.g. status becomes Status
+func propertyName(field string) string {
+	if field == "" {
+		return field
+	}
+	return strings.ToUpper(field[0:1]) + field[1:]
+}
+
+func (q *Query) addCondition(field string, op string, value string, pos int) error {
+	switch strings.ToLower(field) {
+	case "type":
+		if op != ":" && op != "=" {
+			return &SyntaxError{Pos: pos, Msg: fmt.Sprintf("operator %s not valid for type", op)}
+		}
+		q.WhereTypes = append(q.WhereTypes, WhereType{Is: strings.Split(value, ",")})
+		return nil
+	case "board":
+		if op != ":" && op != "=" {
+			return &SyntaxError{Pos: pos, Msg: fmt.Sprintf("operator %s not valid for board", op)}
+		}
+		q.WhereBoardIs = append(q.WhereBoardIs, strings.Split(value, ",")...)
+		return nil
+	case "sort":
+		if op != ":" && op != "=" {
+			return &SyntaxError{Pos: pos, Msg: fmt.Sprintf("operator %s not valid for sort", op)}
+		}
+		for _, f := range strings.Split(value, ",") {
+			if strings.HasPrefix(f, "-") {
+				q.OrderBy = append(q.OrderBy, OrderBy{Field: f[1:], Desc: true})
+			} else {
+				q.OrderBy = append(q.OrderBy, OrderBy{Field: f})
+			}
+		}
+		return nil
+	case "group":
+		if op != ":" && op != "=" {
+			return &SyntaxError{Pos: pos, Msg: fmt.Sprintf("operator %s not valid for group", op)}
+		}
+		q.GroupBy = append(q.GroupBy, strings.Split(value, ",")...)
+		return nil
+	case "limit":
+		limit, err := strconv.Atoi(value)
+		if (op != ":" && op != "=") || err != nil || limit < 0 {
+			return &SyntaxError{Pos: pos, Msg: fmt.Sprintf("invalid limit %s", value)}
+		}
+		q.Limit = limit
+		return nil
+	}
+
+	where, err := whereProperty(field, op, value, pos)
+	if err != nil {
+		return err
+	}
+	q.WhereProperties = append(q.WhereProperties, where)
+	return nil
+}
+
+func whereProperty(field string, op string, value string, pos int) (WhereProperty, error) {
+	switch strings.ToLower(field) {
+	case "has", "no":
+		if op != ":" && op != "=" {
+			return WhereProperty{}, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("operator %s not valid for %s", op, field)}
+		}
+		if strings.ToLower(field) == "has" {
+			return WhereProperty{Name: propertyName(value), Op: OpExists}, nil
+		}
+		return WhereProperty{Name: propertyName(value), Op: OpMissing}, nil
+	case "board", "sort", "group", "limit":
+		return WhereProperty{}, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("%s cannot be combined with OR, NOT or parentheses", field)}
+	}
+
+	where := WhereProperty{Name: propertyName(field)}
+	switch op {
+	case ":", "=":
+		switch {
+		case strings.HasPrefix(value, "!"):
+			where.ValueIsNoneOf = strings.Split(value[1:], ",")
+		case strings.Contains(value, ".."):
+			bounds := strings.SplitN(value, "..", 2)
+			if bounds[0] == "" || bounds[1] == "" {
+				return where, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("invalid range %s", value)}
+			}
+			where.Op, where.Value, where.To = OpBetween, bounds[0], bounds[1]
+		case len(value) > 1 && strings.HasSuffix(value, "*") && !strings.Contains(value, ","):
+			where.Op, where.Value = OpPrefix, value[:len(value)-1]
+		default:
+			where.ValueIsAnyOf = strings.Split(value, ",")
+		}
+	case "!=":
+		where.ValueIsNoneOf = strings.Split(value, ",")
+	case ">":
+		where.Op, where.Value = OpGreater, value
+	case ">=":
+		where.Op, where.Value = OpGreaterOrEqual, value
+	case "<":
+		where.Op, where.Value = OpLess, value
+	case "<=":
+		where.Op, where.Value = OpLessOrEqual, value
+	case "~":
+		if _, err := regexp.Compile(value); err != nil {
+			return where, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("invalid regular expression %s", value)}
+		}
+		where.Op, where.Value = OpRegex, value
+	}
+	return where, nil
+}
@@ -0,0 +1,80 @@
+package query
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	q, err := Parse(`type:feature owner:@bob status:!#Done points>=5 board:sprint-* "login bug"`)
+	assert.Nil(t, err)
+	assert.Equal(t, []WhereType{{Is: []string{"feature"}}}, q.WhereTypes)
+	assert.Equal(t, []string{"sprint-*"}, q.WhereBoardIs)
+	assert.Equal(t, []string{"login", "bug"}, q.Keys)
+	assert.Equal(t, []string{"login bug"}, q.Phrases)
+	assert.Equal(t, []WhereProperty{
+		{Name: "Owner", ValueIsAnyOf: []string{"@bob"}},
+		{Name: "Status", ValueIsNoneOf: []string{"#Done"}},
+		{Name: "Points", Op: OpGreaterOrEqual, Value: "5"},
+	}, q.WhereProperties)
+
+	q, err = Parse(`status:"In progress" deploy`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"In progress"}, q.WhereProperties[0].ValueIsAnyOf)
+	assert.Equal(t, []string{"deploy"}, q.Keys)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse(`type:feature "login bug`)
+	assert.Equal(t, &SyntaxError{Pos: 13, Msg: "unterminated quote"}, err)
+
+	_, err = Parse(`owner: type:bug`)
+	assert.Equal(t, 6, err.(*SyntaxError).Pos)
+
+	_, err = Parse(`type>feature`)
+	assert.Equal(t, 0, err.(*SyntaxError).Pos)
+
+	_, err = Parse(`login, bug`)
+	assert.Equal(t, 5, err.(*SyntaxError).Pos)
+}
+
+func TestParseOrder(t *testing.T) {
+	q, err := Parse(`type:bug sort:-points,name group:owner limit:20`)
+	assert.Nil(t, err)
+	assert.Equal(t, []OrderBy{{Field: "points", Desc: true}, {Field: "name"}}, q.OrderBy)
+	assert.Equal(t, []string{"owner"}, q.GroupBy)
+	assert.Equal(t, 20, q.Limit)
+}
+
+func TestParseGroups(t *testing.T) {
+	q, err := Parse(`type:bug (owner:@bob OR owner:@alice) NOT status:#Done points:3..8 has:epic`)
+	assert.Nil(t, err)
+	assert.Equal(t, []WhereType{{Is: []string{"bug"}}}, q.WhereTypes)
+	assert.Equal(t, &Condition{All: []Condition{
+		{Any: []Condition{
+			{Property: &WhereProperty{Name: "Owner", ValueIsAnyOf: []string{"@bob"}}},
+			{Property: &WhereProperty{Name: "Owner", ValueIsAnyOf: []string{"@alice"}}},
+		}},
+		{Not: &Condition{Property: &WhereProperty{Name: "Status", ValueIsAnyOf: []string{"#Done"}}}},
+	}}, q.Where)
+	assert.Equal(t, []WhereProperty{
+		{Name: "Points", Op: OpBetween, Value: "3", To: "8"},
+		{Name: "Epic", Op: OpExists},
+	}, q.WhereProperties)
+
+	q, err = Parse(`end<today+7d OR title~^Login`)
+	assert.Nil(t, err)
+	assert.Equal(t, &Condition{All: []Condition{{Any: []Condition{
+		{Property: &WhereProperty{Name: "End", Op: OpLess, Value: "today+7d"}},
+		{Property: &WhereProperty{Name: "Title", Op: OpRegex, Value: "^Login"}},
+	}}}}, q.Where)
+
+	_, err = Parse(`(owner:@bob OR login)`)
+	assert.Equal(t, 15, err.(*SyntaxError).Pos)
+
+	_, err = Parse(`(owner:@bob`)
+	assert.Equal(t, &SyntaxError{Pos: 0, Msg: "missing ')'"}, err)
+
+	_, err = Parse(`title~(`)
+	assert.Equal(t, 0, err.(*SyntaxError).Pos)
+}
@@ -0,0 +1,300 @@
+package query
+
+import (
+	"almost-scrum/core"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dateLayouts are the formats accepted for dates in properties and queries
+var dateLayouts = []string{"2006-01-02", "2006-01-02 15:04", time.RFC3339, "02/01/2006"}
+
+var relativeDate = regexp.MustCompile(`^(today|now)(?:([+-])(\d+)([dwmy]))?$`)
+
+var regexCache sync.Map
+
+// propertyKinds maps the name of a model to the kinds of its properties
+type propertyKinds map[string]map[string]core.PropertyKind
+
+func getPropertyKinds(project *core.Project) propertyKinds {
+	kinds := make(propertyKinds, len(project.Models))
+	for _, model := range project.Models {
+		m := make(map[string]core.PropertyKind, len(model.Properties))
+		for _, propertyDef := range model.Properties {
+			m[propertyDef.Name] = propertyDef.Kind
+		}
+		kinds[model.Name] = m
+	}
+	return kinds
+}
+
+func (k propertyKinds) get(taskRef *TaskRef, name string) core.PropertyKind {
+	if strings.ToLower(name) == "modtime" {
+		return core.KindDate
+	}
+	return k[taskRef.Task.Properties["Type"]][name]
+}
+
+// getValue returns the value of a property. Name, Title, Board, Project and ModTime refer to the task itself.
+func getValue(taskRef *TaskRef, name string) (string, bool) {
+	switch strings.ToLower(name) {
+	case "project":
+		return taskRef.Project, true
+	case "name":
+		return taskRef.Name, true
+	case "title":
+		if idx := strings.IndexByte(taskRef.Name, '.'); idx >= 0 {
+			return taskRef.Name[idx+1:], true
+		}
+		return taskRef.Name, true
+	case "board":
+		return taskRef.Board, true
+	case "modtime":
+		return taskRef.ModTime.Format(time.RFC3339), true
+	}
+	value, found := taskRef.Task.Properties[name]
+	return value, found
+}
+
+// parseDate converts absolute dates and dates relative to today (e.g. today-7d, today+1m) in time
+func parseDate(s string, now time.Time) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if m := relativeDate.FindStringSubmatch(strings.ToLower(s)); m != nil {
+		t := now
+		if m[1] == "today" {
+			t = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		}
+		if m[2] == "" {
+			return t, true
+		}
+		n, _ := strconv.Atoi(m[3])
+		if m[2] == "-" {
+			n = -n
+		}
+		switch m[4] {
+		case "d":
+			return t.AddDate(0, 0, n), true
+		case "w":
+			return t.AddDate(0, 0, 7*n), true
+		case "m":
+			return t.AddDate(0, n, 0), true
+		default:
+			return t.AddDate(n, 0, 0), true
+		}
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseNumber reads the value of a numeric property. Percentages can have the % sign, e.g. 50%.
+func parseNumber(value string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+}
+
+// compareTyped compares two values according to the kind of the property. It returns false when
+// the values cannot be compared, e.g. an Int property that contains text.
+func compareTyped(kind core.PropertyKind, value string, other string) (int, bool) {
+	now := time.Now()
+	if kind == core.KindDate || relativeDate.MatchString(strings.ToLower(strings.TrimSpace(other))) {
+		t1, ok1 := parseDate(value, now)
+		t2, ok2 := parseDate(other, now)
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		switch {
+		case t1.Before(t2):
+			return -1, true
+		case t1.After(t2):
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+
+	if kind == core.KindInt || kind == core.KindPercentage {
+		v1, err1 := parseNumber(value)
+		v2, err2 := parseNumber(other)
+		if err1 != nil || err2 != nil {
+			return 0, false
+		}
+		switch {
+		case v1 < v2:
+			return -1, true
+		case v1 > v2:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+	return compareValues(value, other), true
+}
+
+func getRegex(pattern string) (*regexp.Regexp, error) {
+	if r, found := regexCache.Load(pattern); found {
+		return r.(*regexp.Regexp), nil
+	}
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(pattern, r)
+	return r, nil
+}
+
+// truth is the result of a condition in three-valued logic. A condition on a property that
+// the task does not have is unknown: NOT unknown is unknown, false AND unknown is false and
+// true OR unknown is true. A task matches a query only when the result is true.
+type truth int8
+
+const (
+	isFalse truth = iota
+	isUnknown
+	isTrue
+)
+
+func toTruth(b bool) truth {
+	if b {
+		return isTrue
+	}
+	return isFalse
+}
+
+// evaluator checks conditions on tasks and collects the problems found, e.g. an Int property
+// with a value that is not a number
+type evaluator struct {
+	kinds  propertyKinds
+	errors []QueryError
+}
+
+func (e *evaluator) addError(taskRef *TaskRef, format string, args ...interface{}) {
+	e.errors = append(e.errors, QueryError{
+		Board: taskRef.Board,
+		Name:  taskRef.Name,
+		Error: fmt.Sprintf(format, args...),
+	})
+}
+
+func (e *evaluator) compareValue(taskRef *TaskRef, where WhereProperty, value string) truth {
+	kind := e.kinds.get(taskRef, where.Name)
+	switch where.Op {
+	case OpPrefix:
+		return toTruth(strings.HasPrefix(strings.ToLower(value), strings.ToLower(where.Value)))
+	case OpRegex:
+		r, err := getRegex(where.Value)
+		if err != nil {
+			e.addError(taskRef, "invalid regular expression %s: %v", where.Value, err)
+			return isUnknown
+		}
+		return toTruth(r.MatchString(value))
+	case OpBetween:
+		low, ok1 := compareTyped(kind, value, where.Value)
+		high, ok2 := compareTyped(kind, value, where.To)
+		if !ok1 || !ok2 {
+			e.addError(taskRef, "cannot compare %s '%s' with %s..%s", where.Name, value, where.Value, where.To)
+			return isUnknown
+		}
+		return toTruth(low >= 0 && high <= 0)
+	}
+
+	cmp, ok := compareTyped(kind, value, where.Value)
+	if !ok {
+		e.addError(taskRef, "cannot compare %s '%s' with %s", where.Name, value, where.Value)
+		return isUnknown
+	}
+	switch where.Op {
+	case OpGreater:
+		return toTruth(cmp > 0)
+	case OpGreaterOrEqual:
+		return toTruth(cmp >= 0)
+	case OpLess:
+		return toTruth(cmp < 0)
+	case OpLessOrEqual:
+		return toTruth(cmp <= 0)
+	case OpEqual:
+		return toTruth(cmp == 0)
+	case OpNotEqual:
+		return toTruth(cmp != 0)
+	default:
+		e.addError(taskRef, "unknown operator %s", where.Op)
+		return isUnknown
+	}
+}
+
+func (e *evaluator) matchProperty(taskRef *TaskRef, where WhereProperty) truth {
+	value, found := getValue(taskRef, where.Name)
+	switch where.Op {
+	case OpIsPresent:
+		return toTruth(found)
+	case OpIsMissing:
+		return toTruth(!found)
+	case OpExists:
+		return toTruth(found && strings.TrimSpace(value) != "")
+	case OpMissing:
+		return toTruth(!found || strings.TrimSpace(value) == "")
+	}
+	if !found {
+		return isUnknown
+	}
+
+	if len(where.ValueIsAnyOf) > 0 && !core.HasStringInSlice(where.ValueIsAnyOf, value) {
+		return isFalse
+	}
+	if len(where.ValueIsNoneOf) > 0 && core.HasStringInSlice(where.ValueIsNoneOf, value) {
+		return isFalse
+	}
+	if where.Op != "" {
+		return e.compareValue(taskRef, where, value)
+	}
+	return isTrue
+}
+
+func (e *evaluator) matchCondition(taskRef *TaskRef, condition *Condition) truth {
+	result := isTrue
+	if condition.Property != nil {
+		result = e.matchProperty(taskRef, *condition.Property)
+	}
+	for i := range condition.All {
+		if result == isFalse {
+			return isFalse
+		}
+		if t := e.matchCondition(taskRef, &condition.All[i]); t < result {
+			result = t
+		}
+	}
+	if len(condition.Any) > 0 && result != isFalse {
+		any := isFalse
+		for i := range condition.Any {
+			if t := e.matchCondition(taskRef, &condition.Any[i]); t > any {
+				any = t
+			}
+		}
+		if any < result {
+			result = any
+		}
+	}
+	if condition.Not != nil && result != isFalse {
+		if t := isTrue - e.matchCondition(taskRef, condition.Not); t < result {
+			result = t
+		}
+	}
+	return result
+}
+
+// hasRequiredProperties returns true when all the conditions are true for the task
+func (e *evaluator) hasRequiredProperties(taskRef *TaskRef, whereProperties []WhereProperty) bool {
+	for _, whereProperty := range whereProperties {
+		if e.matchProperty(taskRef, whereProperty) != isTrue {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package query
+
+import (
+	"almost-scrum/core"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2021, 3, 10, 15, 30, 0, 0, time.UTC)
+
+	d, ok := parseDate("today-7d", now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2021, 3, 3, 0, 0, 0, 0, time.UTC), d)
+
+	d, ok = parseDate("today+1m", now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2021, 4, 10, 0, 0, 0, 0, time.UTC), d)
+
+	d, ok = parseDate("2021-01-02", now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), d)
+
+	_, ok = parseDate("yesterday", now)
+	assert.False(t, ok)
+}
+
+func TestMatchCondition(t *testing.T) {
+	kinds := propertyKinds{"feature": {"Points": core.KindInt, "Progress": core.KindPercentage,
+		"End": core.KindDate}}
+	ref := &TaskRef{
+		Board: "backlog",
+		Name:  "1.Login page",
+		Task: core.Task{Properties: map[string]string{
+			"Type": "feature", "Points": "12", "Progress": "80%", "End": "2021-01-15", "Owner": "@bob", "Epic": "",
+		}},
+	}
+	e := evaluator{kinds: kinds}
+	match := func(condition Condition) bool {
+		return e.matchCondition(ref, &condition) == isTrue
+	}
+
+	assert.True(t, match(Condition{Property: &WhereProperty{Name: "Points", Op: OpGreater, Value: "9"}}))
+	assert.True(t, match(Condition{Property: &WhereProperty{Name: "Points", Op: OpBetween, Value: "5", To: "12"}}))
+	assert.True(t, match(Condition{Property: &WhereProperty{Name: "Progress", Op: OpGreater, Value: "9"}}))
+	assert.True(t, match(Condition{Property: &WhereProperty{Name: "End", Op: OpLess, Value: "today"}}))
+	assert.True(t, match(Condition{Property: &WhereProperty{Name: "Epic", Op: OpMissing}}))
+	assert.True(t, match(Condition{Property: &WhereProperty{Name: "Title", Op: OpRegex, Value: "^Login"}}))
+	assert.True(t, match(Condition{Property: &WhereProperty{Name: "Owner", Op: OpPrefix, Value: "@b"}}))
+	assert.False(t, match(Condition{Not: &Condition{Property: &WhereProperty{Name: "Owner", ValueIsAnyOf: []string{"@bob"}}}}))
+	assert.True(t, match(Condition{Any: []Condition{
+		{Property: &WhereProperty{Name: "Owner", ValueIsAnyOf: []string{"@alice"}}},
+		{Property: &WhereProperty{Name: "Points", Op: OpGreaterOrEqual, Value: "12"}},
+	}}))
+}
+
+func TestMissingProperties(t *testing.T) {
+	e := evaluator{kinds: propertyKinds{"feature": {"Points": core.KindInt}}}
+	ref := &TaskRef{
+		Board: "backlog",
+		Name:  "1.Login page",
+		Task:  core.Task{Properties: map[string]string{"Type": "feature", "Points": "many"}},
+	}
+	status := &WhereProperty{Name: "Status", ValueIsAnyOf: []string{"#Done"}}
+	notDone := &WhereProperty{Name: "Status", ValueIsNoneOf: []string{"#Done"}}
+	isMissing := &WhereProperty{Name: "Status", Op: OpIsMissing}
+
+	assert.Equal(t, isUnknown, e.matchProperty(ref, *status))
+	assert.Equal(t, isUnknown, e.matchProperty(ref, *notDone))
+	assert.Equal(t, isUnknown, e.matchCondition(ref, &Condition{Not: &Condition{Property: status}}))
+	assert.Equal(t, isTrue, e.matchProperty(ref, *isMissing))
+	assert.Equal(t, isFalse, e.matchProperty(ref, WhereProperty{Name: "Status", Op: OpIsPresent}))
+	assert.Equal(t, isTrue, e.matchCondition(ref, &Condition{Any: []Condition{{Property: notDone}, {Property: isMissing}}}))
+	assert.Equal(t, isFalse, e.matchCondition(ref, &Condition{All: []Condition{{Property: status}, {Property: &WhereProperty{Name: "Status", Op: OpIsPresent}}}}))
+	assert.False(t, e.hasRequiredProperties(ref, []WhereProperty{*notDone}))
+
+	assert.Equal(t, isUnknown, e.matchProperty(ref, WhereProperty{Name: "Points", Op: OpGreater, Value: "3"}))
+	assert.Len(t, e.errors, 1)
+}
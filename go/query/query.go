@@ -4,17 +4,39 @@ import (
 	"almost-scrum/core"
 	"github.com/patrickmn/go-cache"
 	"github.com/sirupsen/logrus"
+	"math"
 	"path"
+	"strconv"
+	"strings"
 	"time"
 )
 
 var queryCache = cache.New(5*time.Minute, 10*time.Minute)
 
+func init() {
+	core.AddTasksListener(invalidateCache)
+}
+
+func invalidateCache(project *core.Project, changes []core.TaskChange) {
+	for _, change := range changes {
+		queryCache.Delete(path.Join(project.Config.UUID, change.Board, change.Name))
+	}
+}
+
+func matchBoard(board string, whereBoardIs []string) bool {
+	for _, pattern := range whereBoardIs {
+		if matched, _ := path.Match(pattern, board); matched {
+			return true
+		}
+	}
+	return false
+}
+
 func filterByBoard(infos []core.TaskInfo, whereBoardIs []string) []core.TaskInfo {
 	var r []core.TaskInfo
 
 	for _, info := range infos {
-		if _, found := core.FindStringInSlice(whereBoardIs, info.Board); found {
+		if matchBoard(info.Board, whereBoardIs) {
 			r = append(r, info)
 		}
 	}
@@ -46,34 +68,99 @@ func getTaskRef(project *core.Project, info core.TaskInfo) (*TaskRef, error) {
 	return &taskRef, nil
 }
 
+// readTaskRef is like getTaskRef but it does not add the task to the cache. It is used when
+// tasks are read once, e.g. for an export.
+func readTaskRef(project *core.Project, info core.TaskInfo) (*TaskRef, error) {
+	key := path.Join(project.Config.UUID, info.Board, info.Name)
+	if t, found := queryCache.Get(key); found && t.(*TaskRef).ModTime == info.ModTime {
+		return t.(*TaskRef), nil
+	}
+
+	task, err := core.GetTask(project, info.Board, info.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &TaskRef{
+		Board:   info.Board,
+		Name:    info.Name,
+		ModTime: info.ModTime,
+		Task:    task,
+	}, nil
+}
+
 func hasValidType(taskRef *TaskRef, types []string) bool {
 	return core.HasStringInSlice(types, taskRef.Task.Properties["Type"])
 }
 
-func hasRequiredProperties(taskRef *TaskRef, whereProperties []WhereProperty) bool {
-	for _, whereProperty := range whereProperties {
-		for name, value := range taskRef.Task.Properties {
-			if whereProperty.Name != name {
-				continue
-			}
+// parseValue returns the value as a number. NaN is not a number, since it cannot be ordered.
+func parseValue(value string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	return v, err == nil && !math.IsNaN(v)
+}
 
-			if len(whereProperty.ValueIsAnyOf) > 0 && !core.HasStringInSlice(whereProperty.ValueIsAnyOf, value) {
-				return false
-			}
+// parseDateValue returns the value as a date when it has one of the absolute date layouts
+func parseDateValue(value string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
 
-			if len(whereProperty.ValueIsNoneOf) > 0 && core.HasStringInSlice(whereProperty.ValueIsNoneOf, value) {
-				return false
-			}
+// compareValues compares numbers when both values are numeric, dates when both are dates (e.g.
+// 02/01/2006) and strings otherwise. Numbers come before dates and dates before text, so that the
+// order is total when sorting and paging mixed values.
+func compareValues(value string, other string) int {
+	v1, ok1 := parseValue(value)
+	v2, ok2 := parseValue(other)
+	switch {
+	case ok1 && ok2 && v1 < v2:
+		return -1
+	case ok1 && ok2 && v1 > v2:
+		return 1
+	case ok1 && ok2:
+		return 0
+	case ok1:
+		return -1
+	case ok2:
+		return 1
+	}
+
+	t1, ok1 := parseDateValue(value)
+	t2, ok2 := parseDateValue(other)
+	switch {
+	case ok1 && ok2 && t1.Before(t2):
+		return -1
+	case ok1 && ok2 && t1.After(t2):
+		return 1
+	case ok1 && ok2:
+		return 0
+	case ok1:
+		return -1
+	case ok2:
+		return 1
+	default:
+		return strings.Compare(value, other)
+	}
+}
+
+func hasPhrases(taskRef *TaskRef, phrases []string) bool {
+	text := strings.ToLower(taskRef.Name + " " + taskRef.Task.Description)
+	for _, phrase := range phrases {
+		if !strings.Contains(text, strings.ToLower(phrase)) {
+			return false
 		}
 	}
 	return true
 }
 
 func selectContent(refs []*TaskRef, select_ Select) []TaskRef {
-	var rs []TaskRef
+	rs := make([]TaskRef, 0, len(refs))
 
 	for _, ref := range refs {
 		r := TaskRef{
+			Project: ref.Project,
 			Board:   ref.Board,
 			Name:    ref.Name,
 			ModTime: ref.ModTime,
@@ -97,36 +184,143 @@ func selectContent(refs []*TaskRef, select_ Select) []TaskRef {
 	return rs
 }
 
+// QueryTasks returns the tasks that match the query. Use Execute to get pages and groups.
 func QueryTasks(project *core.Project, params Query) ([]TaskRef, error) {
-	infos, err := core.ListTasks(project, "", "")
+	result, err := Execute(project, params)
 	if err != nil {
 		return nil, err
 	}
+	return result.Tasks, nil
+}
+
+// Execute runs the query and returns the selected page of tasks and the aggregates for the groups
+func Execute(project *core.Project, params Query) (Result, error) {
+	refs, errors, err := matchTasks(project, params)
+	if err != nil {
+		return Result{}, err
+	}
+	return getResult(refs, errors, params, getPropertyKinds(project))
+}
 
-	validTypes := QueryTypes(project, params.WhereTypes)
+// ExecuteAll runs the query on more projects. Tasks and errors are tagged with the name of the
+// project, which can also be used in ordering and grouping, e.g. groupBy [project].
+func ExecuteAll(projects map[string]*core.Project, params Query) (Result, error) {
+	var refs []*TaskRef
+	errors := []QueryError{}
+	kinds := make(propertyKinds)
+	for name, project := range projects {
+		rs, es, err := matchTasks(project, params)
+		if err != nil {
+			logrus.Warnf("cannot query project %s: %v", name, err)
+			errors = append(errors, QueryError{Project: name, Error: err.Error()})
+			continue
+		}
+		for _, r := range rs {
+			ref := *r
+			ref.Project = name
+			refs = append(refs, &ref)
+		}
+		for _, e := range es {
+			e.Project = name
+			errors = append(errors, e)
+		}
+		for model, m := range getPropertyKinds(project) {
+			kinds[model] = m
+		}
+	}
+	return getResult(refs, errors, params, kinds)
+}
+
+func getResult(refs []*TaskRef, errors []QueryError, params Query, kinds propertyKinds) (Result, error) {
+	var err error
+	sortTasks(refs, params.OrderBy)
+	result := Result{
+		Total:  len(refs),
+		Groups: groupTasks(refs, params.GroupBy, params.Aggregates, kinds),
+		Errors: errors,
+	}
+
+	refs, result.Next, err = getPage(refs, params.OrderBy, params.Cursor, params.Limit)
+	if err != nil {
+		return Result{}, err
+	}
+	result.Tasks = selectContent(refs, params.Select)
+	return result, nil
+}
+
+// matcher checks the conditions of a query on single tasks
+type matcher struct {
+	evaluator
+	params     Query
+	validTypes []string
+}
+
+// prepareQuery returns the candidate tasks for the query, i.e. the tasks that contain the keys
+// in the allowed boards, and the matcher for the other conditions
+func prepareQuery(project *core.Project, params Query) (*matcher, []core.TaskInfo, error) {
+	// stop words are not in the index, so a task would never match all the keys; phrases still
+	// check the exact text
+	infos, err := core.SearchTask(project, "", true, core.IndexedKeys(project, params.Keys)...)
+	if err != nil {
+		return nil, nil, err
+	}
 	if params.WhereBoardIs != nil {
 		infos = filterByBoard(infos, params.WhereBoardIs)
 	}
 
+	m := &matcher{
+		evaluator: evaluator{
+			kinds:  getPropertyKinds(project),
+			errors: []QueryError{},
+		},
+		params:     params,
+		validTypes: QueryTypes(project, params.WhereTypes),
+	}
+	return m, infos, nil
+}
+
+func (m *matcher) addReadError(project *core.Project, info core.TaskInfo, err error) {
+	logrus.Errorf("cannot get task ref for %s/%s/%s", project.Config.Public.Name, info.Board, info.Name)
+	m.errors = append(m.errors, QueryError{Board: info.Board, Name: info.Name, Error: err.Error()})
+}
+
+func (m *matcher) match(taskRef *TaskRef) bool {
+	params := m.params
+	if len(params.WhereTypes) > 0 && !hasValidType(taskRef, m.validTypes) {
+		return false
+	}
+	if len(params.WhereProperties) > 0 && !m.hasRequiredProperties(taskRef, params.WhereProperties) {
+		return false
+	}
+	if params.Where != nil && m.matchCondition(taskRef, params.Where) != isTrue {
+		return false
+	}
+	if len(params.Phrases) > 0 && !hasPhrases(taskRef, params.Phrases) {
+		return false
+	}
+	return true
+}
+
+// matchTasks returns the tasks that match the query and the problems found on the other tasks
+func matchTasks(project *core.Project, params Query) ([]*TaskRef, []QueryError, error) {
+	m, infos, err := prepareQuery(project, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var refs []*TaskRef
 	for _, info := range infos {
 		taskRef, err := getTaskRef(project, info)
 		if err != nil {
-			logrus.Errorf("cannot get task ref for %s/%s/%s", project.Config.Public.Name, taskRef.Board, taskRef.Name)
-			continue
-		}
-
-		if len(params.WhereTypes) > 0 && !hasValidType(taskRef, validTypes) {
+			m.addReadError(project, info, err)
 			continue
 		}
-
-		if len(params.WhereProperties) > 0 && !hasRequiredProperties(taskRef, params.WhereProperties) {
-			continue
+		if m.match(taskRef) {
+			refs = append(refs, taskRef)
 		}
-		refs = append(refs, taskRef)
 	}
 
-	return selectContent(refs, params.Select), nil
+	return refs, m.errors, nil
 }
 
 func QueryTypes(project *core.Project, params []WhereType) []string {
@@ -158,3 +352,31 @@ func QueryTypes(project *core.Project, params []WhereType) []string {
 	}
 	return r
 }
+
+// RunFilter executes a saved filter of the user or of the project
+func RunFilter(project *core.Project, user string, name string) ([]TaskRef, error) {
+	filter, err := core.GetFilter(project, user, name)
+	if err != nil {
+		return nil, err
+	}
+	return runFilter(project, filter)
+}
+
+// RunView executes the shared filter behind a virtual board. Personal filters with the same name
+// are ignored, so that all users see the same tasks in the board.
+func RunView(project *core.Project, name string) ([]TaskRef, error) {
+	filter, err := core.GetSharedFilter(project, name)
+	if err != nil {
+		return nil, err
+	}
+	return runFilter(project, filter)
+}
+
+func runFilter(project *core.Project, filter core.Filter) ([]TaskRef, error) {
+	q, err := Parse(filter.Query)
+	if err != nil {
+		logrus.Warnf("invalid query in filter %s: %v", filter.Name, err)
+		return nil, err
+	}
+	return QueryTasks(project, q)
+}
@@ -2,6 +2,7 @@ package query
 
 import (
 	"almost-scrum/core"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"os"
@@ -58,4 +59,51 @@ func TestQueryTasks(t *testing.T) {
 	err = core.ShredProject(p)
 	assert.Nilf(t, err, "Cannot shred project: %w", err)
 
-}
\ No newline at end of file
+}
+func TestQueryMissingProperties(t *testing.T) {
+	folder, _ := ioutil.TempDir(os.TempDir(), "stg")
+
+	p, err := core.InitProject(folder, []string{"scrum", "issue-tracker", "meeting-notes",
+		"library-integration", "library-issue-tracker"})
+	assert.Nilf(t, err, "Cannot initialize project: %w", err)
+
+	for i, model := range p.Models {
+		complete, _, err := core.CreateTask(p, "backlog", "Complete", model.Name, core.GetSystemUser())
+		assert.Nilf(t, err, "Cannot create task: %w", err)
+		emptyName := fmt.Sprintf("%d.Empty", 100+i)
+		empty := &core.Task{Properties: map[string]string{"Type": model.Name}}
+		err = core.SetTask(p, "backlog", emptyName, empty)
+		assert.Nilf(t, err, "Cannot save task: %w", err)
+
+		for _, propertyDef := range model.Properties {
+			if propertyDef.Name == "Type" {
+				continue
+			}
+			value := complete.Properties[propertyDef.Name]
+			query := func(where WhereProperty) []TaskRef {
+				tr, err := QueryTasks(p, Query{WhereTypes: []WhereType{{Is: []string{model.Name}}},
+					WhereProperties: []WhereProperty{where}})
+				assert.Nil(t, err)
+				return tr
+			}
+
+			tr := query(WhereProperty{Name: propertyDef.Name, ValueIsAnyOf: []string{value}})
+			assert.Equalf(t, 1, len(tr), "%s.%s is any of", model.Name, propertyDef.Name)
+
+			tr = query(WhereProperty{Name: propertyDef.Name, ValueIsNoneOf: []string{value + "-"}})
+			assert.Equalf(t, 1, len(tr), "%s.%s is none of", model.Name, propertyDef.Name)
+
+			tr = query(WhereProperty{Name: propertyDef.Name, Op: OpIsMissing})
+			assert.Equalf(t, 1, len(tr), "%s.%s is missing", model.Name, propertyDef.Name)
+			if len(tr) == 1 {
+				assert.Equal(t, emptyName, tr[0].Name)
+			}
+
+			tr = query(WhereProperty{Name: propertyDef.Name, Op: OpIsPresent})
+			assert.Equalf(t, 1, len(tr), "%s.%s is present", model.Name, propertyDef.Name)
+		}
+	}
+
+	err = core.ShredProject(p)
+	assert.Nilf(t, err, "Cannot shred project: %w", err)
+}
@@ -0,0 +1,226 @@
+package query
+
+import (
+	"almost-scrum/core"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned when the cursor in a query cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// defaultOrder is used when a query does not define any order: recently modified tasks come first
+var defaultOrder = []OrderBy{{Field: "modTime", Desc: true}}
+
+// cursor is the position after the last task of a page. It contains the values of the sort
+// fields so that the next page is correct even when tasks are added or removed.
+type cursor struct {
+	Values  []string `json:"v"`
+	Project string   `json:"p,omitempty"`
+	Board   string   `json:"b"`
+	Name    string   `json:"n"`
+}
+
+// getId identifies a task in the results of a query, also across projects
+func getId(project, board, name string) string {
+	return project + "\x00" + board + "\x00" + name
+}
+
+// fieldValue returns the value of a sort or group field. Fields other than name, title, board and
+// modTime are task properties, e.g. owner or Status.
+func fieldValue(ref *TaskRef, field string) string {
+	if field == "modTime" {
+		return ref.ModTime.UTC().Format(time.RFC3339Nano)
+	}
+	value, _ := getValue(ref, propertyName(field))
+	return value
+}
+
+func getOrder(orderBy []OrderBy) []OrderBy {
+	if len(orderBy) == 0 {
+		return defaultOrder
+	}
+	return orderBy
+}
+
+// compareKeys compares the sort values of two tasks. Project, board and name break ties so that
+// the order is always the same.
+func compareKeys(orderBy []OrderBy, values1 []string, id1 string, values2 []string, id2 string) int {
+	for i, order := range orderBy {
+		cmp := compareValues(values1[i], values2[i])
+		if order.Desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return strings.Compare(id1, id2)
+}
+
+func sortValues(ref *TaskRef, orderBy []OrderBy) []string {
+	values := make([]string, len(orderBy))
+	for i, order := range orderBy {
+		values[i] = fieldValue(ref, order.Field)
+	}
+	return values
+}
+
+func sortTasks(refs []*TaskRef, orderBy []OrderBy) {
+	orderBy = getOrder(orderBy)
+	values := make(map[*TaskRef][]string, len(refs))
+	for _, ref := range refs {
+		values[ref] = sortValues(ref, orderBy)
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		r1, r2 := refs[i], refs[j]
+		return compareKeys(orderBy, values[r1], getId(r1.Project, r1.Board, r1.Name),
+			values[r2], getId(r2.Project, r2.Board, r2.Name)) < 0
+	})
+}
+
+func encodeCursor(ref *TaskRef, orderBy []OrderBy) string {
+	data, _ := json.Marshal(cursor{
+		Values:  sortValues(ref, orderBy),
+		Project: ref.Project,
+		Board:   ref.Board,
+		Name:    ref.Name,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string, orderBy []OrderBy) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || len(c.Values) != len(orderBy) {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// getPage returns up to limit tasks after the cursor and the cursor for the next page. The next
+// cursor is empty when there are no more tasks. A limit of 0 returns all tasks.
+func getPage(refs []*TaskRef, orderBy []OrderBy, from string, limit int) ([]*TaskRef, string, error) {
+	orderBy = getOrder(orderBy)
+	if from != "" {
+		c, err := decodeCursor(from, orderBy)
+		if err != nil {
+			return nil, "", err
+		}
+		id := getId(c.Project, c.Board, c.Name)
+		start := sort.Search(len(refs), func(i int) bool {
+			r := refs[i]
+			return compareKeys(orderBy, sortValues(r, orderBy), getId(r.Project, r.Board, r.Name), c.Values, id) > 0
+		})
+		refs = refs[start:]
+	}
+
+	if limit <= 0 || len(refs) <= limit {
+		return refs, "", nil
+	}
+	refs = refs[:limit]
+	return refs, encodeCursor(refs[limit-1], orderBy), nil
+}
+
+// aggregateName is the key of an aggregate in Group.Values, e.g. sum(Points)
+func aggregateName(aggregate Aggregate) string {
+	if aggregate.Property == "" {
+		return string(aggregate.Func)
+	}
+	return string(aggregate.Func) + "(" + propertyName(aggregate.Property) + ")"
+}
+
+// aggregate computes the aggregate on the tasks. Sum and avg use the values of Int and Percentage
+// properties and the numeric values of properties without a kind.
+func aggregate(refs []*TaskRef, aggregate Aggregate, kinds propertyKinds) float64 {
+	switch aggregate.Func {
+	case AggregateCount:
+		return float64(len(refs))
+	case AggregateSum, AggregateAvg:
+		name := propertyName(aggregate.Property)
+		sum, cnt := 0.0, 0
+		for _, ref := range refs {
+			switch kinds.get(ref, name) {
+			case "", core.KindInt, core.KindPercentage:
+			default:
+				continue
+			}
+			if v, err := parseNumber(ref.Task.Properties[name]); err == nil && !math.IsNaN(v) {
+				sum += v
+				cnt++
+			}
+		}
+		if aggregate.Func == AggregateSum {
+			return sum
+		}
+		if cnt == 0 {
+			return 0
+		}
+		return sum / float64(cnt)
+	case AggregateDone:
+		done, total := 0, 0
+		for _, ref := range refs {
+			for _, part := range ref.Task.Parts {
+				if part.Done {
+					done++
+				}
+				total++
+			}
+		}
+		if total == 0 {
+			return 0
+		}
+		return float64(done) / float64(total)
+	default:
+		return 0
+	}
+}
+
+// groupTasks splits the tasks by the values of the groupBy fields and computes the aggregates
+// for each group. Groups follow the order of their first task.
+func groupTasks(refs []*TaskRef, groupBy []string, aggregates []Aggregate, kinds propertyKinds) []Group {
+	if len(groupBy) == 0 && len(aggregates) == 0 {
+		return nil
+	}
+
+	var keys []string
+	members := make(map[string][]*TaskRef)
+	groupKeys := make(map[string]map[string]string)
+	for _, ref := range refs {
+		key := make(map[string]string, len(groupBy))
+		values := make([]string, len(groupBy))
+		for i, field := range groupBy {
+			values[i] = fieldValue(ref, field)
+			key[field] = values[i]
+		}
+		id := strings.Join(values, "\x00")
+		if _, found := members[id]; !found {
+			keys = append(keys, id)
+			groupKeys[id] = key
+		}
+		members[id] = append(members[id], ref)
+	}
+
+	groups := make([]Group, 0, len(keys))
+	for _, id := range keys {
+		group := Group{
+			Key:    groupKeys[id],
+			Count:  len(members[id]),
+			Values: make(map[string]float64, len(aggregates)),
+		}
+		for _, a := range aggregates {
+			group.Values[aggregateName(a)] = aggregate(members[id], a, kinds)
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
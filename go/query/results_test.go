@@ -0,0 +1,115 @@
+package query
+
+import (
+	"almost-scrum/core"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"testing"
+)
+
+func getTestRefs() []*TaskRef {
+	newRef := func(name string, owner string, points string, done bool) *TaskRef {
+		return &TaskRef{
+			Board: "backlog",
+			Name:  name,
+			Task: core.Task{
+				Properties: map[string]string{"Owner": owner, "Points": points},
+				Parts:      []core.Part{{Description: "part", Done: done}},
+			},
+		}
+	}
+	return []*TaskRef{
+		newRef("1.Login", "@bob", "3", true),
+		newRef("2.Logout", "@alice", "10", false),
+		newRef("3.Signup", "@bob", "5", false),
+	}
+}
+
+func TestSortAndPage(t *testing.T) {
+	refs := getTestRefs()
+	orderBy := []OrderBy{{Field: "points", Desc: true}}
+	sortTasks(refs, orderBy)
+	assert.Equal(t, "2.Logout", refs[0].Name)
+	assert.Equal(t, "1.Login", refs[2].Name)
+
+	page, next, err := getPage(refs, orderBy, "", 2)
+	assert.Nil(t, err)
+	assert.Len(t, page, 2)
+	assert.NotEmpty(t, next)
+
+	page, next, err = getPage(refs, orderBy, next, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.Login", page[0].Name)
+	assert.Empty(t, next)
+
+	_, _, err = getPage(refs, orderBy, "invalid", 2)
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestCompareValues(t *testing.T) {
+	values := []string{"b", "10", "NaN", "a", "2", "", "-1.5"}
+	sort.Slice(values, func(i, j int) bool { return compareValues(values[i], values[j]) < 0 })
+	assert.Equal(t, []string{"-1.5", "2", "10", "", "NaN", "a", "b"}, values)
+	for _, v1 := range values {
+		for _, v2 := range values {
+			assert.Equal(t, compareValues(v1, v2), -compareValues(v2, v1), "%s %s", v1, v2)
+		}
+	}
+}
+
+func TestCompareDates(t *testing.T) {
+	values := []string{"text", "02/01/2021", "2020-12-31", "1", "01/02/2021"}
+	sort.Slice(values, func(i, j int) bool { return compareValues(values[i], values[j]) < 0 })
+	assert.Equal(t, []string{"1", "2020-12-31", "02/01/2021", "01/02/2021", "text"}, values)
+}
+
+func TestAggregateKinds(t *testing.T) {
+	refs := getTestRefs()
+	for i, ref := range refs {
+		ref.Task.Properties["Type"] = "Feature"
+		ref.Task.Properties["Progress"] = []string{"50%", "25%", "n/a"}[i]
+		ref.Task.Properties["Code"] = "10"
+	}
+	kinds := propertyKinds{"Feature": {"Progress": core.KindPercentage, "Code": core.KindString}}
+
+	assert.Equal(t, 75.0, aggregate(refs, Aggregate{Func: AggregateSum, Property: "progress"}, kinds))
+	assert.Equal(t, 37.5, aggregate(refs, Aggregate{Func: AggregateAvg, Property: "progress"}, kinds))
+	assert.Equal(t, 0.0, aggregate(refs, Aggregate{Func: AggregateSum, Property: "code"}, kinds))
+	assert.Equal(t, 18.0, aggregate(refs, Aggregate{Func: AggregateSum, Property: "points"}, kinds))
+}
+
+func TestGroupTasks(t *testing.T) {
+	groups := groupTasks(getTestRefs(), []string{"owner"}, []Aggregate{
+		{Func: AggregateSum, Property: "points"},
+		{Func: AggregateDone},
+	}, nil)
+	assert.Len(t, groups, 2)
+	assert.Equal(t, map[string]string{"owner": "@bob"}, groups[0].Key)
+	assert.Equal(t, 2, groups[0].Count)
+	assert.Equal(t, 8.0, groups[0].Values["sum(Points)"])
+	assert.Equal(t, 0.5, groups[0].Values["done"])
+}
+
+func TestPageAcrossProjects(t *testing.T) {
+	var refs []*TaskRef
+	for _, project := range []string{"beta", "alpha"} {
+		for _, ref := range getTestRefs() {
+			ref.Project = project
+			refs = append(refs, ref)
+		}
+	}
+	orderBy := []OrderBy{{Field: "owner"}}
+	sortTasks(refs, orderBy)
+	assert.Equal(t, "alpha", refs[0].Project)
+	assert.Equal(t, "beta", refs[1].Project)
+
+	page, next, err := getPage(refs, orderBy, "", 1)
+	assert.Nil(t, err)
+	page, _, err = getPage(refs, orderBy, next, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "beta", page[0].Project)
+	assert.Equal(t, "2.Logout", page[0].Name)
+
+	groups := groupTasks(refs, []string{"project"}, nil, nil)
+	assert.Equal(t, map[string]string{"project": "alpha"}, groups[0].Key)
+}
@@ -0,0 +1,184 @@
+package query
+
+import (
+	"almost-scrum/core"
+	"github.com/google/uuid"
+	"github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	EventAdd    = "add"
+	EventUpdate = "update"
+	EventRemove = "remove"
+	// EventReset is sent when a client resumes from an event that is no longer available.
+	// The client must discard its tasks and use the ones in the following add events.
+	EventReset = "reset"
+)
+
+// SubscriptionHistory is the number of events kept for clients that reconnect
+const SubscriptionHistory = 256
+
+// SubscriptionTimeout is the time a subscription is kept without any client connected
+const SubscriptionTimeout = 10 * time.Minute
+
+// Event is a change in the result of a subscribed query. IDs are increasing in a subscription
+// and clients use the last seen ID to resume after a reconnect.
+type Event struct {
+	ID   uint64  `json:"id"`
+	Type string  `json:"type"`
+	Task TaskRef `json:"task"`
+}
+
+// Subscription is a query whose result is kept updated when tasks change on disk, either through
+// the web API, the CLI, git or federation sync.
+type Subscription struct {
+	ID      string
+	Project *core.Project
+	Query   Query
+	state   State
+	events  []Event
+	lastID  uint64
+	wake    chan bool
+	lock    sync.Mutex
+}
+
+var subscriptions = cache.New(SubscriptionTimeout, time.Minute)
+
+func init() {
+	core.AddTasksListener(updateSubscriptions)
+}
+
+func getKey(ref *TaskRef) string {
+	return path.Join(ref.Board, ref.Name)
+}
+
+// Subscribe registers the query and returns the subscription with the tasks that match now
+func Subscribe(project *core.Project, q Query) (*Subscription, []TaskRef, error) {
+	q.Limit, q.Cursor = 0, ""
+	refs, _, err := matchTasks(project, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s := &Subscription{
+		ID:      uuid.New().String(),
+		Project: project,
+		Query:   q,
+		state:   make(State, len(refs)),
+		wake:    make(chan bool, 1),
+	}
+	for _, ref := range refs {
+		s.state[getKey(ref)] = ref
+	}
+	subscriptions.SetDefault(s.ID, s)
+
+	sortTasks(refs, q.OrderBy)
+	logrus.Debugf("New subscription %s on project %s with %d tasks", s.ID, project.Config.Public.Name, len(refs))
+	return s, selectContent(refs, q.Select), nil
+}
+
+// GetSubscription returns the subscription with the given id if it has not expired
+func GetSubscription(project *core.Project, id string) (*Subscription, bool) {
+	s, found := subscriptions.Get(id)
+	if !found || s.(*Subscription).Project.Config.UUID != project.Config.UUID {
+		return nil, false
+	}
+	subscriptions.SetDefault(id, s)
+	return s.(*Subscription), true
+}
+
+// Unsubscribe removes the subscription
+func Unsubscribe(id string) {
+	subscriptions.Delete(id)
+}
+
+func updateSubscriptions(project *core.Project, _ []core.TaskChange) {
+	for _, item := range subscriptions.Items() {
+		s := item.Object.(*Subscription)
+		if s.Project.Config.UUID == project.Config.UUID {
+			s.update()
+		}
+	}
+}
+
+func (s *Subscription) addEvent(type_ string, ref *TaskRef) {
+	s.lastID++
+	s.events = append(s.events, Event{
+		ID:   s.lastID,
+		Type: type_,
+		Task: selectContent([]*TaskRef{ref}, s.Query.Select)[0],
+	})
+	if len(s.events) > SubscriptionHistory {
+		s.events = s.events[len(s.events)-SubscriptionHistory:]
+	}
+}
+
+// update runs the query again and creates the events for the differences with the last result
+func (s *Subscription) update() {
+	refs, _, err := matchTasks(s.Project, s.Query)
+	if err != nil {
+		logrus.Warnf("cannot update subscription %s: %v", s.ID, err)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	lastID := s.lastID
+	state := make(State, len(refs))
+	for _, ref := range refs {
+		key := getKey(ref)
+		state[key] = ref
+		if old, found := s.state[key]; !found {
+			s.addEvent(EventAdd, ref)
+		} else if !old.ModTime.Equal(ref.ModTime) {
+			s.addEvent(EventUpdate, ref)
+		}
+	}
+	for key, ref := range s.state {
+		if _, found := state[key]; !found {
+			s.addEvent(EventRemove, ref)
+		}
+	}
+	s.state = state
+
+	if s.lastID != lastID {
+		select {
+		case s.wake <- true:
+		default:
+		}
+	}
+}
+
+// EventsAfter returns the events after the given ID. When those events are no longer available,
+// it returns a reset event followed by an add event for each task in the current result.
+func (s *Subscription) EventsAfter(id uint64) []Event {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if id >= s.lastID {
+		return nil
+	}
+	if len(s.events) > 0 && id+1 >= s.events[0].ID {
+		return append([]Event{}, s.events[id+1-s.events[0].ID:]...)
+	}
+
+	events := []Event{{ID: s.lastID, Type: EventReset}}
+	for _, ref := range s.state {
+		events = append(events, Event{
+			ID:   s.lastID,
+			Type: EventAdd,
+			Task: selectContent([]*TaskRef{ref}, s.Query.Select)[0],
+		})
+	}
+	return events
+}
+
+// Wait returns a channel that receives a value when new events are available
+func (s *Subscription) Wait() <-chan bool {
+	return s.wake
+}
@@ -0,0 +1,28 @@
+package query
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEventsAfter(t *testing.T) {
+	s := &Subscription{state: State{}, wake: make(chan bool, 1)}
+	refs := getTestRefs()
+	for _, ref := range refs {
+		s.state[getKey(ref)] = ref
+		s.addEvent(EventAdd, ref)
+	}
+	s.addEvent(EventRemove, refs[0])
+	delete(s.state, getKey(refs[0]))
+
+	events := s.EventsAfter(2)
+	assert.Len(t, events, 2)
+	assert.Equal(t, uint64(3), events[0].ID)
+	assert.Equal(t, EventRemove, events[1].Type)
+	assert.Nil(t, s.EventsAfter(4))
+
+	s.events = s.events[2:]
+	events = s.EventsAfter(1)
+	assert.Equal(t, EventReset, events[0].Type)
+	assert.Len(t, events, 3)
+}
@@ -6,6 +6,7 @@ import (
 )
 
 type TaskRef struct {
+	Project string    `json:"project,omitempty"`
 	Board   string    `json:"board"`
 	Name    string    `json:"name"`
 	ModTime time.Time `json:"modTime"`
@@ -24,10 +25,88 @@ type WhereType struct {
 	HasPropertiesAll []string `json:"hasPropertiesAll"`
 }
 
+type Operator string
+
+const (
+	OpGreater        Operator = "gt"
+	OpGreaterOrEqual Operator = "ge"
+	OpLess           Operator = "lt"
+	OpLessOrEqual    Operator = "le"
+	OpEqual          Operator = "eq"
+	OpNotEqual       Operator = "ne"
+	OpBetween        Operator = "between"
+	OpExists         Operator = "exists"
+	OpMissing        Operator = "missing"
+	OpPrefix         Operator = "prefix"
+	OpRegex          Operator = "regex"
+	OpIsPresent      Operator = "isPresent"
+	OpIsMissing      Operator = "isMissing"
+)
+
+// WhereProperty is a condition on a property. Comparisons use the kind of the property in the
+// model of the task: Int and Percentage properties are compared as numbers and Date properties as
+// dates. Dates can be relative to the current day, e.g. today-7d or today+2w. Between uses Value
+// and To as bounds.
+// When the task does not have the property, the condition is unknown and the task does not match,
+// even for ValueIsNoneOf. IsPresent and IsMissing check if the task has the property; Exists and
+// Missing also consider an empty value as missing.
 type WhereProperty struct {
 	Name          string   `json:"name"`
 	ValueIsAnyOf  []string `json:"valueIsAnyOf"`
 	ValueIsNoneOf []string `json:"valueIsNoneOf"`
+	Op            Operator `json:"op"`
+	Value         string   `json:"value"`
+	To            string   `json:"to"`
+}
+
+// Condition combines property conditions. All the fields that are set must be true: All requires
+// all the conditions, Any at least one of them and Not requires the condition to be false.
+type Condition struct {
+	All      []Condition    `json:"all"`
+	Any      []Condition    `json:"any"`
+	Not      *Condition     `json:"not"`
+	Property *WhereProperty `json:"property"`
+}
+
+type OrderBy struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
+type AggregateFunc string
+
+const (
+	AggregateCount AggregateFunc = "count"
+	AggregateSum   AggregateFunc = "sum"
+	AggregateAvg   AggregateFunc = "avg"
+	AggregateDone  AggregateFunc = "done"
+)
+
+type Aggregate struct {
+	Func     AggregateFunc `json:"func"`
+	Property string        `json:"property"`
+}
+
+type Group struct {
+	Key    map[string]string  `json:"key"`
+	Count  int                `json:"count"`
+	Values map[string]float64 `json:"values"`
+}
+
+// QueryError is a problem found on a task while running a query, e.g. the task cannot be read
+type QueryError struct {
+	Project string `json:"project,omitempty"`
+	Board   string `json:"board"`
+	Name    string `json:"name"`
+	Error   string `json:"error"`
+}
+
+type Result struct {
+	Tasks  []TaskRef    `json:"tasks"`
+	Total  int          `json:"total"`
+	Next   string       `json:"next"`
+	Groups []Group      `json:"groups"`
+	Errors []QueryError `json:"errors"`
 }
 
 type Query struct {
@@ -35,6 +114,14 @@ type Query struct {
 	WhereTypes      []WhereType     `json:"whereTypes"`
 	WhereProperties []WhereProperty `json:"whereProperties"`
 	WhereBoardIs    []string        `json:"whereBoardIs"`
+	Where           *Condition      `json:"where"`
+	Keys            []string        `json:"keys"`
+	Phrases         []string        `json:"phrases"`
+	OrderBy         []OrderBy       `json:"orderBy"`
+	Limit           int             `json:"limit"`
+	Cursor          string          `json:"cursor"`
+	GroupBy         []string        `json:"groupBy"`
+	Aggregates      []Aggregate     `json:"aggregates"`
 }
 
 type State map[string]*TaskRef
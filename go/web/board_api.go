@@ -27,6 +27,7 @@ func listBoardsAPI(c *gin.Context) {
 		c.String(http.StatusInternalServerError, "Cannot list boards: %v", err)
 		return
 	}
+	boards = append(boards, core.ListViewBoards(project)...)
 	logrus.Debugf("listBoardsAPI - List boards in project: %v", boards)
 
 	c.JSON(http.StatusOK, boards)
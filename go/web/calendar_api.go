@@ -0,0 +1,183 @@
+package web
+
+import (
+	"almost-scrum/core"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func calendarRoute(group *gin.RouterGroup) {
+	group.GET("/projects/:project/calendar", getCalendarAPI)
+	group.PUT("/projects/:project/calendar", putCalendarAPI)
+	group.POST("/projects/:project/calendar/import", importCalendarAPI)
+	group.GET("/projects/:project/calendar/capacity", getCapacityAPI)
+	group.GET("/projects/:project/calendar/burndown", getBurndownAPI)
+	group.GET("/projects/:project/calendar/absences/:user", getAbsencesAPI)
+	group.PUT("/projects/:project/calendar/absences/:user", putAbsencesAPI)
+}
+
+func getCalendarAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	config := project.Config.Public.Calendar
+	if len(config.WorkingDays) == 0 {
+		config.WorkingDays = core.DefaultWorkingDays
+	}
+	c.JSON(http.StatusOK, config)
+}
+
+func putCalendarAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	var config core.CalendarConfig
+	if err := c.BindJSON(&config); err != nil {
+		return
+	}
+	for _, holiday := range config.Holidays {
+		if _, err := time.Parse(core.DateLayout, holiday.Date); err != nil {
+			c.String(http.StatusBadRequest, "Invalid date %s: use the format YYYY-MM-DD", holiday.Date)
+			return
+		}
+	}
+	if err := core.SetCalendar(project, config); err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, config)
+}
+
+// importCalendarAPI adds the events of the iCalendar file in the body to the holidays
+func importCalendarAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	data, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	added, err := core.ImportICS(project, data)
+	if err == core.ErrInvalidCalendar {
+		c.String(http.StatusBadRequest, "Invalid iCalendar file")
+		return
+	}
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, added)
+}
+
+// getPeriod reads the from (included) and to (excluded) parameters. To defaults to two weeks after from.
+func getPeriod(c *gin.Context) (time.Time, time.Time, bool) {
+	from, err := time.Parse(core.DateLayout, c.DefaultQuery("from", time.Now().Format(core.DateLayout)))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid date %s: use the format YYYY-MM-DD", c.Query("from"))
+		return from, from, false
+	}
+	to := from.AddDate(0, 0, 14)
+	if t := c.Query("to"); t != "" {
+		if to, err = time.Parse(core.DateLayout, t); err != nil || to.Before(from) {
+			c.String(http.StatusBadRequest, "Invalid date %s: use the format YYYY-MM-DD after from", t)
+			return from, to, false
+		}
+	}
+	return from, to, true
+}
+
+// getCapacityAPI returns the working days of each user between from and to
+func getCapacityAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	from, to, ok := getPeriod(c)
+	if !ok {
+		return
+	}
+	calendar := core.GetCalendar(project)
+	c.JSON(http.StatusOK, gin.H{
+		"workingDays": calendar.WorkingDays("", from, to),
+		"users":       calendar.Capacity(core.GetUserList(project), from, to),
+	})
+}
+
+// getBurndownAPI returns the ideal burndown of total points between from and to
+func getBurndownAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	from, to, ok := getPeriod(c)
+	if !ok {
+		return
+	}
+	total, err := strconv.ParseFloat(c.DefaultQuery("total", "0"), 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid total %s", c.Query("total"))
+		return
+	}
+	c.JSON(http.StatusOK, core.GetCalendar(project).IdealBurndown(total, from, to))
+}
+
+func getAbsencesAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	userInfo, err := core.GetUserInfo(project, c.Param("user"))
+	if err != nil {
+		_ = c.AbortWithError(http.StatusNotFound, err)
+		return
+	}
+	absences := userInfo.Absences
+	if absences == nil {
+		absences = []core.Absence{}
+	}
+	c.JSON(http.StatusOK, absences)
+}
+
+func putAbsencesAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	// users change their own absences; admins can change the absences of everybody
+	user, webUser := c.Param("user"), getWebUser(c)
+	if !strings.EqualFold(strings.TrimPrefix(user, "@"), webUser) && !core.IsAdmin(project, webUser) {
+		c.String(http.StatusForbidden, "User '%s' cannot change the absences of '%s'", webUser, user)
+		return
+	}
+
+	var absences []core.Absence
+	if err := c.BindJSON(&absences); err != nil {
+		return
+	}
+	err := core.SetAbsences(project, user, absences)
+	if err == core.ErrInvalidCalendar {
+		c.String(http.StatusBadRequest, "Invalid absence: use dates in the format YYYY-MM-DD")
+		return
+	}
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, absences)
+}
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"almost-scrum/core"
+	"almost-scrum/query"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// feedsRoute adds the calendar feeds, which are outside the authenticated group because calendar
+// clients cannot log in: access requires the feed token of a user in the URL.
+func feedsRoute(router *gin.Engine, group *gin.RouterGroup) {
+	group.POST("/projects/:project/feeds/token", postFeedTokenAPI)
+	router.GET("/feeds/:project/project.ics", getProjectFeedAPI)
+	router.GET("/feeds/:project/user.ics", getUserFeedAPI)
+}
+
+// postFeedTokenAPI creates a new feed token for the user and returns the URLs of the feeds.
+// The previous token of the user stops working.
+func postFeedTokenAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	token, err := core.NewFeedToken(project, getWebUser(c))
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	base := fmt.Sprintf("%s://%s/feeds/%s", scheme, c.Request.Host, c.Param("project"))
+	c.JSON(http.StatusOK, gin.H{
+		"token":   token,
+		"project": fmt.Sprintf("%s/project.ics?token=%s", base, token),
+		"user":    fmt.Sprintf("%s/user.ics?token=%s", base, token),
+	})
+}
+
+// getFeedProject resolves the project in the URL and the user that owns the token
+func getFeedProject(c *gin.Context) (*core.Project, string) {
+	name := c.Param("project")
+	projectLock.Lock()
+	defer projectLock.Unlock()
+
+	project, found := projectMapping[name]
+	if !found {
+		c.String(http.StatusNotFound, "Project %s not found in configuration", name)
+		return nil, ""
+	}
+	user, found := core.GetFeedUser(project, c.Query("token"))
+	if !found {
+		c.String(http.StatusForbidden, "Invalid feed token")
+		return nil, ""
+	}
+	if _, ok := hasAccess(name, project, user); !ok {
+		c.String(http.StatusForbidden, "No access to project")
+		return nil, ""
+	}
+	return project, user
+}
+
+// writeFeed writes the tasks that match the query in iCalendar format. The holidays of the project are
+// included with the parameter holidays=true.
+func writeFeed(c *gin.Context, project *core.Project, q query.Query) {
+	holidays, _ := strconv.ParseBool(c.DefaultQuery("holidays", "false"))
+	c.Header("Content-Type", query.ExportContentTypes[query.FormatICS])
+	c.Status(http.StatusOK)
+	if err := query.ExportICS(project, q, holidays, c.Writer); err != nil {
+		logrus.Errorf("cannot write calendar feed of %s: %v", project.Config.Public.Name, err)
+		_ = c.Error(err)
+	}
+}
+
+// getProjectFeedAPI returns the tasks with dates of the project in iCalendar format
+func getProjectFeedAPI(c *gin.Context) {
+	project, _ := getFeedProject(c)
+	if project == nil {
+		return
+	}
+	writeFeed(c, project, query.Query{})
+}
+
+// getUserFeedAPI returns the tasks with dates owned by the user of the token in iCalendar format
+func getUserFeedAPI(c *gin.Context) {
+	project, user := getFeedProject(c)
+	if project == nil {
+		return
+	}
+	q := query.Query{
+		WhereProperties: []query.WhereProperty{{Name: "Owner", ValueIsAnyOf: []string{"@" + user}}},
+	}
+	writeFeed(c, project, q)
+}
@@ -0,0 +1,109 @@
+package web
+
+import (
+	"almost-scrum/core"
+	"almost-scrum/query"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+func filtersRoute(group *gin.RouterGroup) {
+	group.GET("/projects/:project/filters", listFiltersAPI)
+	group.PUT("/projects/:project/filters/:name", putFilterAPI)
+	group.DELETE("/projects/:project/filters/:name", deleteFilterAPI)
+	group.GET("/projects/:project/filters/:name/tasks", runFilterAPI)
+}
+
+func listFiltersAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, core.ListFilters(project, getWebUser(c)))
+}
+
+func putFilterAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	var filter core.Filter
+	if err := c.BindJSON(&filter); core.IsErr(err, "Invalid JSON") {
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	filter.Name = c.Param("name")
+	shared, _ := strconv.ParseBool(c.DefaultQuery("shared", "false"))
+	if !canShareFilters(c, project, shared) {
+		return
+	}
+
+	if _, err := query.Parse(filter.Query); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := core.SetFilter(project, getWebUser(c), filter, shared); err != nil {
+		_ = c.Error(err)
+		c.String(http.StatusInternalServerError, "Cannot save filter %s: %v", filter.Name, err)
+		return
+	}
+	c.JSON(http.StatusOK, filter)
+}
+
+func deleteFilterAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	name := c.Param("name")
+	shared, _ := strconv.ParseBool(c.DefaultQuery("shared", "false"))
+	if !canShareFilters(c, project, shared) {
+		return
+	}
+	switch err := core.DeleteFilter(project, getWebUser(c), name, shared); err {
+	case core.ErrNoFound:
+		c.String(http.StatusNotFound, "Filter %s does not exist", name)
+	case nil:
+		c.String(http.StatusOK, "")
+	default:
+		_ = c.Error(err)
+		c.String(http.StatusInternalServerError, "Cannot delete filter %s: %v", name, err)
+	}
+}
+
+// canShareFilters returns false and replies with forbidden when a user other than the admins changes
+// the shared filters, which are also the virtual boards of the project
+func canShareFilters(c *gin.Context, project *core.Project, shared bool) bool {
+	if user := getWebUser(c); shared && !core.IsAdmin(project, user) {
+		c.String(http.StatusForbidden, "User '%s' cannot change the shared filters", user)
+		return false
+	}
+	return true
+}
+
+func runFilterAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	name := c.Param("name")
+	refs, err := query.RunFilter(project, getWebUser(c), name)
+	switch err.(type) {
+	case nil:
+		c.JSON(http.StatusOK, refs)
+	case *query.SyntaxError:
+		c.JSON(http.StatusBadRequest, err)
+	default:
+		if err == core.ErrNoFound {
+			c.String(http.StatusNotFound, "Filter %s does not exist", name)
+		} else {
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
+		}
+	}
+}
@@ -5,23 +5,168 @@ import (
 	"almost-scrum/gantt"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func ganttRoute(group *gin.RouterGroup) {
 	group.GET("/projects/:project/gantt", getGanttTasksAPI)
+	group.GET("/projects/:project/gantt/plan", getGanttPlanAPI)
+	group.POST("/projects/:project/gantt/schedule", postGanttScheduleAPI)
+	group.GET("/projects/:project/gantt/workload", getWorkloadAPI)
+	group.GET("/projects/:project/gantt/baselines", listBaselinesAPI)
+	group.POST("/projects/:project/gantt/baselines", postBaselineAPI)
+	group.GET("/projects/:project/gantt/baselines/:name", getBaselineAPI)
+	group.DELETE("/projects/:project/gantt/baselines/:name", deleteBaselineAPI)
 }
 
 
+// getGanttTasksAPI returns the tasks in the gantt, with the same analysis of the dependencies as the plan
 func getGanttTasksAPI(c *gin.Context) {
 	var project *core.Project
 	if project = getProject(c); project == nil {
 		return
 	}
 
-	if tasks, err := gantt.GetTasks(project); err != nil {
+	if plan, err := gantt.GetPlan(project); err != nil {
 		_ = c.AbortWithError(http.StatusInternalServerError, err)
 	} else {
-		c.JSON(http.StatusOK, tasks)
+		c.JSON(http.StatusOK, plan.Tasks)
 	}
 }
 
+// getGanttPlanAPI returns the tasks with the critical path analysis of their dependencies
+func getGanttPlanAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	if plan, err := gantt.GetPlan(project); err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+	} else {
+		c.JSON(http.StatusOK, plan)
+	}
+}
+
+// postGanttScheduleAPI computes the dates of the tasks from estimates and dependencies. Parameters:
+// from, the first day of the schedule (default today), and apply, to save the dates in the tasks.
+func postGanttScheduleAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	from := time.Now()
+	if f := c.Query("from"); f != "" {
+		var err error
+		if from, err = time.Parse(gantt.DateLayout, f); err != nil {
+			c.String(http.StatusBadRequest, "Invalid date %s: use the format YYYY-MM-DD", f)
+			return
+		}
+	}
+	apply, _ := strconv.ParseBool(c.DefaultQuery("apply", "false"))
+
+	changes, err := gantt.GetSchedule(project, from, apply)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, changes)
+}
+
+func listBaselinesAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	if baselines, err := gantt.ListBaselines(project); err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+	} else {
+		c.JSON(http.StatusOK, baselines)
+	}
+}
+
+// postBaselineAPI saves the current dates and progress of the tasks as a baseline with the given name
+func postBaselineAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	name := c.Query("name")
+	baseline, err := gantt.SaveBaseline(project, name, getWebUser(c))
+	switch err {
+	case nil:
+		c.JSON(http.StatusCreated, baseline)
+	case gantt.ErrInvalidBaseline:
+		c.String(http.StatusBadRequest, "Invalid baseline name %s", name)
+	case core.ErrExists:
+		c.String(http.StatusConflict, "Baseline %s already exists", name)
+	default:
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+	}
+}
+
+// getBaselineAPI returns the baseline dates with the current dates and the slippage of each task
+func getBaselineAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	comparison, err := gantt.GetComparison(project, c.Param("name"))
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, comparison)
+	case core.ErrNoFound, gantt.ErrInvalidBaseline:
+		c.String(http.StatusNotFound, "No baseline %s", c.Param("name"))
+	default:
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+	}
+}
+
+func deleteBaselineAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	err := gantt.DeleteBaseline(project, c.Param("name"))
+	switch err {
+	case nil:
+		c.String(http.StatusOK, "")
+	case core.ErrNoFound, gantt.ErrInvalidBaseline:
+		c.String(http.StatusNotFound, "No baseline %s", c.Param("name"))
+	default:
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+	}
+}
+
+// getWorkloadAPI returns the effort allocated to each user against the capacity. Parameters: from and
+// to (default the next four weeks) and period, day or week.
+func getWorkloadAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	from, to, ok := getPeriod(c)
+	if !ok {
+		return
+	}
+	if c.Query("to") == "" {
+		to = from.AddDate(0, 0, 28)
+	}
+	workload, err := gantt.GetWorkload(project, from, to, c.DefaultQuery("period", gantt.PeriodDay))
+	if err == gantt.ErrInvalidPeriod {
+		c.String(http.StatusBadRequest, "Invalid period %s: use day or week", c.Query("period"))
+		return
+	}
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, workload)
+}
@@ -4,12 +4,14 @@ import (
 	"almost-scrum/core"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 func indexRoute(group *gin.RouterGroup) {
 	group.GET("/projects/:project/index/suggest/:prefix", getSuggestAPI)
+	group.GET("/projects/:project/search", getSearchAPI)
 }
 
 func getSuggestAPI(c *gin.Context) {
@@ -25,3 +27,20 @@ func getSuggestAPI(c *gin.Context) {
 	c.JSON(http.StatusOK, suggestions)
 }
 
+func getSearchAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	keys := strings.Fields(strings.ReplaceAll(c.DefaultQuery("keys", ""), ",", " "))
+	matchAll, _ := strconv.ParseBool(c.DefaultQuery("all", "true"))
+
+	results, err := core.Search(project, matchAll, keys...)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	start, end := getRange(c, len(results))
+	c.JSON(http.StatusOK, results[start:end])
+}
@@ -3,15 +3,19 @@ package web
 import (
 	"almost-scrum/core"
 	"almost-scrum/library"
+	"bytes"
+	"fmt"
 	"github.com/gabriel-vasile/mimetype"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"github.com/skratchdot/open-golang/open"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func libraryRoute(group *gin.RouterGroup) {
@@ -23,6 +27,11 @@ func libraryRoute(group *gin.RouterGroup) {
 	group.DELETE("/projects/:project/library/*path", deleteFileAPI)
 	group.POST("/projects/:project/library-stat", getLibraryItemsAPI)
 	group.POST("/projects/:project/library-book/*path", postLibraryBookAPI)
+	group.GET("/projects/:project/library-search", searchLibraryAPI)
+	group.GET("/projects/:project/library-diff", diffLibraryAPI)
+	group.GET("/projects/:project/library-trash", listTrashAPI)
+	group.POST("/projects/:project/library-trash/:id", restoreTrashAPI)
+	group.DELETE("/projects/:project/library-trash/:id", purgeTrashAPI)
 }
 
 func localOpen(c *gin.Context, path string) {
@@ -37,6 +46,10 @@ func localOpen(c *gin.Context, path string) {
 
 		for _, valid := range core.MimeForLocalAccess {
 			if mime.Is(valid) {
+				// the file may be changed in place, so it must not share the content with other files
+				if err := core.DetachFile(path); err != nil {
+					logrus.Warnf("Cannot detach %s from the blob store: %v", path, err)
+				}
 				if err := open.Start(path); err != nil {
 					c.String(http.StatusInternalServerError, "Cannot run locally: %v", err)
 				} else {
@@ -153,7 +166,11 @@ func uploadFileAPI(c *gin.Context) {
 
 	switch action {
 	case "move":
-		if err := library.MoveFile(project, origin, path); err != nil {
+		if err := library.MoveFile(project, origin, path, owner); err != nil {
+			if err == library.ErrLocked {
+				c.String(http.StatusLocked, "%s is checked out by another user", origin)
+				return
+			}
 			_ = c.Error(err)
 			c.String(http.StatusInternalServerError, "Cannot move %s to %s", origin, path)
 			return
@@ -162,6 +179,10 @@ func uploadFileAPI(c *gin.Context) {
 		c.String(http.StatusOK, "%s", path)
 	case "upgrade":
 		path_, err := library.IncreaseVersion(project, path, owner, public)
+		if err == library.ErrLocked {
+			c.String(http.StatusLocked, "%s is checked out by another user", path)
+			return
+		}
 		if err != nil {
 			_ = c.Error(err)
 			c.String(http.StatusInternalServerError, "Cannot upgrade version: %v", err)
@@ -190,6 +211,10 @@ func uploadFileAPI(c *gin.Context) {
 
 		dest := filepath.Join(path, file.Filename)
 		if _, err = library.SetFileInLibrary(project, dest, reader, owner, public); err != nil {
+			if err == library.ErrLocked {
+				c.String(http.StatusLocked, "%s is checked out by another user", dest)
+				return
+			}
 			_ = c.Error(err)
 			c.String(http.StatusInternalServerError, "Cannot upload to %s", path)
 			return
@@ -197,6 +222,51 @@ func uploadFileAPI(c *gin.Context) {
 		logrus.Infof("Name %s uploaded in %s, owner %s, public %t",
 			file.Filename, path, owner, public)
 		getLibraryAPI(c)
+	case "checkout":
+		hours, _ := strconv.Atoi(c.DefaultQuery("hours", "0"))
+		lock, err := library.CheckOut(project, path, owner, time.Duration(hours)*time.Hour)
+		switch err {
+		case nil:
+			c.JSON(http.StatusOK, lock)
+		case library.ErrLocked:
+			c.String(http.StatusLocked, "%s is checked out by another user", path)
+		case core.ErrInvalidType:
+			c.String(http.StatusBadRequest, "only documents can be checked out")
+		default:
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
+		}
+	case "checkin":
+		// the new content is optional; without it the new version has the content of the current one
+		var reader multipart.File
+		if file, err := c.FormFile("file"); err == nil {
+			if reader, err = file.Open(); err != nil {
+				_ = c.AbortWithError(http.StatusInternalServerError, err)
+				return
+			}
+			defer reader.Close()
+		}
+		path_, err := library.CheckIn(project, path, owner, reader, public)
+		switch err {
+		case nil:
+			c.String(http.StatusOK, path_)
+		case library.ErrLocked:
+			c.String(http.StatusLocked, "%s is checked out by another user", path)
+		case library.ErrNotLocked:
+			c.String(http.StatusConflict, "%s is not checked out", path)
+		default:
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
+		}
+	case "unlock":
+		switch err := library.Unlock(project, path, owner); err {
+		case nil:
+			c.String(http.StatusOK, "")
+		case library.ErrLocked:
+			c.String(http.StatusForbidden, "only the user holding the lock or an admin can unlock %s", path)
+		case library.ErrNotLocked:
+			c.String(http.StatusConflict, "%s is not checked out", path)
+		default:
+			_ = c.AbortWithError(http.StatusInternalServerError, err)
+		}
 	default:
 		c.String(http.StatusBadRequest, "invalid action %s", action)
 
@@ -211,7 +281,11 @@ func deleteFileAPI(c *gin.Context) {
 	}
 
 	path := c.Param("path")
-	if err := library.DeleteFile(project, path); err != nil {
+	if err := library.DeleteFile(project, path, getWebUser(c)); err != nil {
+		if err == library.ErrLocked {
+			c.String(http.StatusLocked, "%s is checked out by another user", path)
+			return
+		}
 		logrus.Warnf("Cannot delete path %s: %v", path, err)
 		_ = c.Error(err)
 		c.String(http.StatusInternalServerError, "Cannot delete file")
@@ -255,6 +329,23 @@ func postLibraryBookAPI(c *gin.Context) {
 		return
 	}
 
+	if c.Query("format") == "epub" {
+		var book bytes.Buffer
+		if err := library.CreateEPUB(project, path, settings, &book); err != nil {
+			logrus.Warnf("Cannot create epub for path %s: %v", path, err)
+			_ = c.Error(err)
+			c.String(http.StatusInternalServerError, "Cannot create book")
+			return
+		}
+		name := settings.Title
+		if name == "" {
+			name = filepath.Base(path)
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".epub"))
+		c.Data(http.StatusOK, library.EPUBMime, book.Bytes())
+		return
+	}
+
 	book, err := library.CreateBook(project, path, settings)
 	if err != nil {
 		logrus.Warnf("Cannot create book for path %s: %v", path, err)
@@ -265,3 +356,99 @@ func postLibraryBookAPI(c *gin.Context) {
 	c.Writer.Header().Set("Content-Type", gin.MIMEHTML)
 	c.String(http.StatusOK, book)
 }
+
+// searchLibraryAPI returns the documents in the library that contain the words in q, ranked and
+// with snippets. The parameter folder limits the search to a folder.
+func searchLibraryAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	hits, err := library.Search(project, c.Query("q"), c.Query("folder"))
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	start, end := getRange(c, len(hits))
+	c.JSON(http.StatusOK, hits[start:end])
+}
+
+func diffLibraryAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	diff, err := library.CompareVersions(project, c.Query("from"), c.Query("to"))
+	switch err {
+	case nil:
+	case core.ErrNoFound:
+		c.String(http.StatusNotFound, "version not found")
+		return
+	case core.ErrInvalidType:
+		c.String(http.StatusBadRequest, "cannot compare this type of document")
+		return
+	default:
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	switch c.Query("format") {
+	case "unified":
+		c.Data(http.StatusOK, "text/x-diff; charset=utf-8", []byte(diff.Unified))
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(diff.HTML))
+	default:
+		c.JSON(http.StatusOK, diff)
+	}
+}
+
+func listTrashAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	items, err := library.ListTrash(project)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func restoreTrashAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	item, err := library.Restore(project, c.Param("id"))
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, item)
+	case core.ErrNoFound:
+		c.String(http.StatusNotFound, "item not found in trash")
+	case core.ErrExists:
+		c.String(http.StatusConflict, "%s already exists in the library", item.Path)
+	default:
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+	}
+}
+
+func purgeTrashAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	switch err := library.Purge(project, c.Param("id")); err {
+	case nil:
+		c.String(http.StatusOK, "")
+	case core.ErrNoFound:
+		c.String(http.StatusNotFound, "item not found in trash")
+	default:
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,64 @@
+package web
+
+import (
+	"almost-scrum/query"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// dashboardLimit is the number of recent tasks returned by the portfolio dashboard
+const dashboardLimit = 20
+
+func portfolioRoute(group *gin.RouterGroup) {
+	group.POST("/portfolio/query", postPortfolioQueryAPI)
+	group.GET("/portfolio/dashboard", getPortfolioDashboardAPI)
+}
+
+func executePortfolio(c *gin.Context, q query.Query) {
+	result, err := query.ExecuteAll(getProjects(c), q)
+	if err == query.ErrInvalidCursor {
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// postPortfolioQueryAPI runs the query on all the projects the user can access. Each task is
+// tagged with its project.
+func postPortfolioQueryAPI(c *gin.Context) {
+	q, ok := bindQuery(c)
+	if !ok {
+		return
+	}
+	executePortfolio(c, q)
+}
+
+// getPortfolioDashboardAPI returns the tasks of the user in all projects, grouped by project.
+// The parameter q replaces the default query (owner:@user) with a textual query.
+func getPortfolioDashboardAPI(c *gin.Context) {
+	text := c.DefaultQuery("q", "owner:@"+getWebUser(c))
+	q, err := query.Parse(text)
+	if syntaxError, ok := err.(*query.SyntaxError); ok {
+		c.JSON(http.StatusBadRequest, syntaxError)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(dashboardLimit)))
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid limit %s", c.Query("limit"))
+		return
+	}
+
+	q.Select = query.Select{Properties: true}
+	q.GroupBy = append([]string{"project"}, q.GroupBy...)
+	q.Aggregates = append(q.Aggregates,
+		query.Aggregate{Func: query.AggregateCount},
+		query.Aggregate{Func: query.AggregateDone})
+	q.Limit = limit
+	executePortfolio(c, q)
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"sync"
@@ -27,6 +28,19 @@ type NoAccess struct {
 	Users   []string `json:"users"`
 }
 
+// hasAccess checks if the user is in the list of users of the project. The caller must hold projectLock.
+func hasAccess(name string, project *core.Project, user string) ([]string, bool) {
+	users := projectUsers[name]
+	if _, found := core.FindStringInSlice(users, user); !found {
+		users = core.GetUserList(project)
+		projectUsers[name] = users
+		if _, found := core.FindStringInSlice(users, user); !found {
+			return users, false
+		}
+	}
+	return users, true
+}
+
 // getProject resolves the URL parameters
 func getProject(c *gin.Context) *core.Project {
 	name := c.Param("project")
@@ -41,26 +55,35 @@ func getProject(c *gin.Context) *core.Project {
 	}
 
 	user := getWebUser(c)
-
-	users := projectUsers[name]
-	if _, found := core.FindStringInSlice(users, user); !found {
-		users = core.GetUserList(project)
-		projectUsers[name] = users
-		if _, found := core.FindStringInSlice(users, user); !found {
-			noAccess := NoAccess{
-				Message: "No access to project",
-				Users:   users,
-			}
-			logrus.Warnf("User %s has no access to project %s. Valid users [%s]", user, name,
-				strings.Join(users, " "))
-			c.JSON(http.StatusForbidden, noAccess)
-			return nil
+	if users, ok := hasAccess(name, project, user); !ok {
+		noAccess := NoAccess{
+			Message: "No access to project",
+			Users:   users,
 		}
+		logrus.Warnf("User %s has no access to project %s. Valid users [%s]", user, name,
+			strings.Join(users, " "))
+		c.JSON(http.StatusForbidden, noAccess)
+		return nil
 	}
 
 	return project
 }
 
+// getProjects returns the projects the user of the request can access
+func getProjects(c *gin.Context) map[string]*core.Project {
+	projectLock.Lock()
+	defer projectLock.Unlock()
+
+	user := getWebUser(c)
+	projects := make(map[string]*core.Project)
+	for name, project := range projectMapping {
+		if _, ok := hasAccess(name, project, user); ok {
+			projects[name] = project
+		}
+	}
+	return projects
+}
+
 func openProject(name string, path string) (*core.Project, error) {
 	if p, found := projectMapping[name]; found {
 		return p, nil
@@ -72,6 +95,9 @@ func openProject(name string, path string) (*core.Project, error) {
 	}
 
 	_ = core.ReIndex(project)
+	if err := core.StartWatcher(project); err != nil {
+		logrus.Warnf("Cannot watch project %s: index is updated only on web changes: %v", name, err)
+	}
 	users := core.GetUserList(project)
 
 	projectLock.Lock()
@@ -193,6 +219,10 @@ func createProjectInFolder(c *gin.Context, name string, path string, templates [
 	}
 	logrus.Debugf("User %s added to project %s", user, name)
 
+	if err := core.StartWatcher(project); err != nil {
+		logrus.Warnf("Cannot watch project %s: index is updated only on web changes: %v", name, err)
+	}
+
 	projectLock.Lock()
 	defer projectLock.Unlock()
 	projectMapping[name] = project
@@ -312,6 +342,16 @@ func getProjectInfoAPI(c *gin.Context) {
 	c.JSON(http.StatusOK, info)
 }
 
+// changed returns true when two lists in the project configuration differ. An empty list and a missing
+// one are the same.
+func changed(current interface{}, update interface{}) bool {
+	c, u := reflect.ValueOf(current), reflect.ValueOf(update)
+	if c.Len() == 0 && u.Len() == 0 {
+		return false
+	}
+	return !reflect.DeepEqual(current, update)
+}
+
 func putProjectInfoAPI(c *gin.Context) {
 	var project *core.Project
 	if project = getProject(c); project == nil {
@@ -324,11 +364,26 @@ func putProjectInfoAPI(c *gin.Context) {
 		return
 	}
 
+	project.ConfigMutex.Lock()
+	current := project.Config.Public
+	if user := getWebUser(c); !core.IsAdmin(project, user) &&
+		(changed(current.Admins, info.Config.Admins) || changed(current.Filters, info.Config.Filters)) {
+		project.ConfigMutex.Unlock()
+		c.String(http.StatusForbidden, "User '%s' cannot change the admins or the shared filters", user)
+		return
+	}
 	project.Config.Public = info.Config
-	if err := core.WriteProjectConfig(project.Path, &project.Config); err != nil {
+	err := core.WriteProjectConfig(project.Path, &project.Config)
+	project.ConfigMutex.Unlock()
+	if err != nil {
 		_ = c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
+	if core.SetAnalyzer(project) {
+		go func() {
+			_ = core.ReIndex(project)
+		}()
+	}
 
 	c.JSON(http.StatusOK, info)
 }
@@ -3,33 +3,228 @@ package web
 import (
 	"almost-scrum/core"
 	"almost-scrum/query"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func queryRoute(group *gin.RouterGroup) {
 	group.POST("/projects/:project/query/tasks", postQueryTasksAPI)
+	group.POST("/projects/:project/query/export", exportTasksAPI)
+	group.GET("/projects/:project/query/export", exportTasksAPI)
+	group.POST("/projects/:project/query/subscriptions", postSubscriptionAPI)
+	group.GET("/projects/:project/query/subscriptions/:id/events", getSubscriptionEventsAPI)
+	group.DELETE("/projects/:project/query/subscriptions/:id", deleteSubscriptionAPI)
 }
 
+// keepAliveInterval is the time between comments sent on idle event streams
+const keepAliveInterval = 30 * time.Second
+
+type SubscriptionInfo struct {
+	ID    string          `json:"id"`
+	Tasks []query.TaskRef `json:"tasks"`
+}
+
+// bindQuery reads the query from the body of the request. The body is either a JSON query
+// or a JSON string in the textual query language (e.g. "type:feature owner:@bob")
+func bindQuery(c *gin.Context) (query.Query, bool) {
+	var q query.Query
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return q, false
+	}
+
+	text := strings.TrimSpace(string(body))
+	if strings.HasPrefix(text, "\"") {
+		if err := json.Unmarshal([]byte(text), &text); err != nil {
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+			return q, false
+		}
+		q, err = query.Parse(text)
+		if syntaxError, ok := err.(*query.SyntaxError); ok {
+			c.JSON(http.StatusBadRequest, syntaxError)
+			return q, false
+		}
+		return q, true
+	}
+
+	if err := json.Unmarshal(body, &q); err != nil {
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return q, false
+	}
+	return q, true
+}
 
 func postQueryTasksAPI(c *gin.Context) {
 	var project *core.Project
-	var q query.Query
 	if project = getProject(c); project == nil {
 		return
 	}
 
-	if err := c.BindJSON(&q); err != nil {
+	q, ok := bindQuery(c)
+	if !ok {
+		return
+	}
+
+	result, err := query.Execute(project, q)
+	if err == query.ErrInvalidCursor {
+		_ = c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
 		_ = c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	ts, err := query.QueryTasks(project, q)
+	// Plain queries keep returning the list of tasks, with the number of errors in a header; pages,
+	// groups and the result flag need the full result
+	_, full := c.GetQuery("result")
+	if full || q.Limit > 0 || q.Cursor != "" || len(q.GroupBy) > 0 || len(q.Aggregates) > 0 {
+		c.JSON(http.StatusOK, result)
+	} else {
+		c.Header("X-Query-Errors", strconv.Itoa(len(result.Errors)))
+		c.JSON(http.StatusOK, result.Tasks)
+	}
+}
+
+func postSubscriptionAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	q, ok := bindQuery(c)
+	if !ok {
+		return
+	}
+
+	s, ts, err := query.Subscribe(project, q)
 	if err != nil {
 		_ = c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
+	c.JSON(http.StatusCreated, SubscriptionInfo{ID: s.ID, Tasks: ts})
+}
+
+// getSubscriptionEventsAPI streams the events of a subscription as Server-Sent Events. Clients
+// resume after a reconnect with the Last-Event-ID header or the lastEventId parameter.
+func getSubscriptionEventsAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	s, found := query.GetSubscription(project, c.Param("id"))
+	if !found {
+		c.String(http.StatusNotFound, "Subscription %s not found", c.Param("id"))
+		return
+	}
+
+	lastEventId := c.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.DefaultQuery("lastEventId", "0")
+	}
+	lastID, err := strconv.ParseUint(lastEventId, 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid event id %s", lastEventId)
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		for _, event := range s.EventsAfter(lastID) {
+			c.Render(-1, sse.Event{
+				Id:    strconv.FormatUint(event.ID, 10),
+				Event: event.Type,
+				Data:  event.Task,
+			})
+			lastID = event.ID
+		}
+		c.Writer.Flush()
 
-	c.JSON(http.StatusOK, ts)
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-s.Wait():
+		case <-keepAlive.C:
+			if _, found := query.GetSubscription(project, s.ID); !found {
+				return false
+			}
+			_, _ = io.WriteString(w, ": keep-alive\n\n")
+		}
+		return true
+	})
 }
 
+func deleteSubscriptionAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	if _, found := query.GetSubscription(project, c.Param("id")); !found {
+		c.String(http.StatusNotFound, "Subscription %s not found", c.Param("id"))
+		return
+	}
+	query.Unsubscribe(c.Param("id"))
+	c.String(http.StatusOK, "")
+}
+
+// exportTasksAPI streams the tasks that match the query as a file. The query is in the body or,
+// for GET, in the parameter q in the textual language. Parameters: format (csv, jsonl, md, xlsx)
+// and fields, a comma separated list of columns.
+func exportTasksAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	var q query.Query
+	if c.Request.Method == http.MethodGet {
+		var err error
+		q, err = query.Parse(c.Query("q"))
+		if syntaxError, ok := err.(*query.SyntaxError); ok {
+			c.JSON(http.StatusBadRequest, syntaxError)
+			return
+		}
+	} else {
+		var ok bool
+		if q, ok = bindQuery(c); !ok {
+			return
+		}
+	}
+
+	format := c.DefaultQuery("format", query.FormatCSV)
+	contentType, found := query.ExportContentTypes[format]
+	if !found {
+		c.String(http.StatusBadRequest, "Unsupported format %s", format)
+		return
+	}
+	var columns []string
+	if fields := c.Query("fields"); fields != "" {
+		columns = strings.Split(fields, ",")
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"",
+		project.Config.Public.Name, format))
+	c.Status(http.StatusOK)
+	if err := query.Export(project, q, format, columns, c.Writer); err != nil {
+		logrus.Errorf("cannot export tasks of %s: %v", project.Config.Public.Name, err)
+		_ = c.Error(err)
+	}
+}
@@ -164,8 +164,14 @@ func StartServer(port string, logLevel string, autoExit_ bool, args []string) {
 	gitRoute(v1)
 	fedRoute(v1)
 	ganttRoute(v1)
+	calendarRoute(v1)
+	storageRoute(v1)
 	queryRoute(v1)
+	filtersRoute(v1)
+	portfolioRoute(v1)
 	chatRoute(v1)
+	feedsRoute(r, v1)
+	webdavRoute(r)
 
 	ashUrl = fmt.Sprintf("http://127.0.0.1:%s", port)
 	if false {open.Start(ashUrl)}
@@ -0,0 +1,47 @@
+package web
+
+import (
+	"almost-scrum/core"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func storageRoute(group *gin.RouterGroup) {
+	group.GET("/projects/:project/storage", getStorageAPI)
+	group.POST("/projects/:project/storage/gc", collectBlobsAPI)
+	group.POST("/projects/:project/storage/dedup", deduplicateAPI)
+}
+
+func getStorageAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, core.GetStorageUsage(project))
+}
+
+func collectBlobsAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	removed, freed := core.CollectBlobs(project)
+	c.JSON(http.StatusOK, gin.H{"removed": removed, "freed": freed})
+}
+
+func deduplicateAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	linked, err := core.Deduplicate(project)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"linked": linked})
+}
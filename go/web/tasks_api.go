@@ -2,6 +2,7 @@ package web
 
 import (
 	"almost-scrum/core"
+	"almost-scrum/query"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -58,6 +59,10 @@ func listTaskAPI(c *gin.Context) {
 	if board == "~" {
 		board = ""
 	}
+	if core.IsViewBoard(board) {
+		listViewTasks(c, project, board)
+		return
+	}
 
 	_, isProperties := c.GetQuery("properties")
 	if isProperties {
@@ -92,6 +97,34 @@ func listTaskAPI(c *gin.Context) {
 	}
 }
 
+// listViewTasks returns the tasks of a virtual board, i.e. the result of a shared filter
+func listViewTasks(c *gin.Context, project *core.Project, board string) {
+	name := strings.TrimPrefix(board, core.ViewBoardPrefix)
+	refs, err := query.RunView(project, name)
+	if err == core.ErrNoFound {
+		c.String(http.StatusNotFound, "Board %s does not exist", board)
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Internal Error %v", err)
+		return
+	}
+
+	infos := make([]core.TaskInfo, 0, len(refs))
+	for _, ref := range refs {
+		id, _ := core.ExtractTaskId(ref.Name)
+		infos = append(infos, core.TaskInfo{
+			ID:      id,
+			Board:   ref.Board,
+			Name:    ref.Name,
+			ModTime: ref.ModTime,
+		})
+	}
+	start, end := getRange(c, len(infos))
+	infos = infos[start:end]
+	c.JSON(http.StatusOK, &infos)
+}
+
 func getTaskAPI(c *gin.Context) {
 	var project *core.Project
 	if project = getProject(c); project == nil {
@@ -146,7 +179,7 @@ func postTaskAPI(c *gin.Context) {
 			_ = c.AbortWithError(http.StatusInternalServerError, err)
 			return
 		}
-		_ = core.ReIndex(project)
+		core.QueueReIndex(project, board, name)
 		c.String(http.StatusOK, name)
 		return
 	}
@@ -169,7 +202,8 @@ func postTaskAPI(c *gin.Context) {
 			oldBoard, oldName, board, name ) {
 		_ = c.AbortWithError(http.StatusInternalServerError, err)
 	}
-	_ = core.ReIndex(project)
+	core.QueueReIndex(project, oldBoard, oldName)
+	core.QueueReIndex(project, board, name)
 	c.String(http.StatusOK, filepath.Join(board, name))
 }
 
@@ -191,7 +225,7 @@ func putTaskAPI(c *gin.Context) {
 		c.String(http.StatusInternalServerError, "Cannot update task %s", name)
 		return
 	}
-	_ = core.ReIndex(project)
+	core.QueueReIndex(project, board, name)
 	c.String(http.StatusOK, "")
 }
 
@@ -209,6 +243,7 @@ func deleteTaskAPI(c *gin.Context) {
 		_ = c.Error(err)
 		c.String(http.StatusNotFound, "Task %s/%s does not exist", board, name)
 	case nil:
+		core.QueueReIndex(project, board, name)
 		c.JSON(http.StatusOK, story)
 	default:
 		c.String(http.StatusInternalServerError, "Internal Error %v", err)
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"almost-scrum/core"
+	"almost-scrum/library"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/webdav"
+)
+
+// davMethods are the HTTP methods used by WebDAV clients
+var davMethods = []string{"OPTIONS", "GET", "HEAD", "PUT", "DELETE", "PROPFIND", "PROPPATCH", "MKCOL",
+	"COPY", "MOVE", "LOCK", "UNLOCK"}
+
+// davLocks keeps the WebDAV locks of each project. These are the short locks clients take while
+// saving a file and are different from the check-out of documents.
+var davLocks = make(map[string]webdav.LockSystem)
+var davLocksMutex sync.Mutex
+
+// webdavRoute exposes the library of each project at /dav/<project>. WebDAV clients do not support
+// tokens, so users authenticate with basic authentication and the same credentials of the login.
+func webdavRoute(r *gin.Engine) {
+	for _, method := range davMethods {
+		r.Handle(method, "/dav/:project", davAuth, webdavAPI)
+		r.Handle(method, "/dav/:project/*path", davAuth, webdavAPI)
+	}
+}
+
+func davAuth(c *gin.Context) {
+	user, password, ok := c.Request.BasicAuth()
+	if !ok || !core.CheckUser(user, password) {
+		if ok {
+			logrus.Warnf("Failed WebDAV authentication for user %s", user)
+		}
+		c.Header("WWW-Authenticate", `Basic realm="Almost Realm"`)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	c.Set(identityKey, &User{UserName: user})
+}
+
+func getDavLocks(name string) webdav.LockSystem {
+	davLocksMutex.Lock()
+	defer davLocksMutex.Unlock()
+
+	locks, found := davLocks[name]
+	if !found {
+		locks = webdav.NewMemLS()
+		davLocks[name] = locks
+	}
+	return locks
+}
+
+func webdavAPI(c *gin.Context) {
+	var project *core.Project
+	if project = getProject(c); project == nil {
+		return
+	}
+
+	name := c.Param("project")
+	user := getWebUser(c)
+	handler := webdav.Handler{
+		Prefix:     "/dav/" + name,
+		FileSystem: library.NewDavFS(project, user),
+		LockSystem: getDavLocks(name),
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				logrus.Warnf("WebDAV %s %s by %s failed: %v", r.Method, r.URL.Path, user, err)
+			}
+		},
+	}
+	handler.ServeHTTP(c.Writer, c.Request)
+}